@@ -5,13 +5,15 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"io"
+	"launcher/network"
 	"net/http"
+	"sync"
 )
 
 var (
-	url         = "https://bc97-78-98-240-12.eu.ngrok.io/asset/"
-	indexUrl    = url + "index/"
-	previewUrlF = url + "preview/%s/%s"
+	client  = http.DefaultClient
+	baseUrl = network.DefaultConfig().Endpoints.Backend
+	lock    sync.RWMutex
 )
 
 type WardrobeIndex map[string]WardrobeEntry
@@ -20,14 +22,29 @@ type WardrobeEntry struct {
 	ID string `json:"id"`
 }
 
+// Configure sets the http client and backend url used by the package
+func Configure(cfg network.Config) error {
+	c, err := network.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	client = c
+	baseUrl = cfg.WithDefaults().Endpoints.Backend
+	return nil
+}
+
 func GetWardrobeIndex() (WardrobeIndex, error) {
-	r, err := http.Get(indexUrl)
+	c, base := current()
+	r, err := c.Get(base + "/asset/index/")
 	if err != nil {
 		return WardrobeIndex{}, errors.New(err.Error())
 	}
+	defer r.Body.Close()
 	b, err := io.ReadAll(r.Body)
 
-	if r.StatusCode != 200 {
+	if r.StatusCode != 200 || err != nil {
 		return WardrobeIndex{}, errors.New("failed to contact api")
 	}
 
@@ -37,5 +54,14 @@ func GetWardrobeIndex() (WardrobeIndex, error) {
 }
 
 func (a *WardrobeEntry) GetPreviewLink() string {
-	return fmt.Sprintf(previewUrlF, a.ID[0:2], a.ID)
+	_, base := current()
+	return fmt.Sprintf(base+"/asset/preview/%s/%s", a.ID[0:2], a.ID)
+}
+
+/* PRIVATE REGION */
+
+func current() (*http.Client, string) {
+	lock.RLock()
+	defer lock.RUnlock()
+	return client, baseUrl
 }
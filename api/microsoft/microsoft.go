@@ -8,9 +8,11 @@ import (
 	"github.com/AzureAD/microsoft-authentication-library-for-go/apps/public"
 	"github.com/pkg/errors"
 	"io/ioutil"
+	"launcher/network"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
@@ -54,11 +56,31 @@ type xblResponseDisplayClaims struct {
 
 const msalClientId = "048f6903-f7d2-47b7-8d7d-47a2fa08b0f7"
 
+var (
+	client    = http.DefaultClient
+	endpoints = network.DefaultConfig().Endpoints
+	lock      sync.RWMutex
+)
+
+// Configure sets the http client and the identity, xbox and minecraft endpoints used by the package
+func Configure(cfg network.Config) error {
+	c, err := network.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	client = c
+	endpoints = cfg.WithDefaults().Endpoints
+	return nil
+}
+
 func (h *MSAuthHandle) GetMinecraftProfile() (MinecraftProfile, error) {
-	req, _ := http.NewRequest("GET", "https://api.minecraftservices.com/minecraft/profile", nil)
+	c, e := current()
+	req, _ := http.NewRequest("GET", e.Minecraft+"/minecraft/profile", nil)
 	req.Header.Set("Authorization", "Bearer "+h.AccessToken)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.Do(req)
 	if err != nil {
 		return MinecraftProfile{}, err
 	}
@@ -84,7 +106,7 @@ var (
 )
 
 func IsCachePresent(dir string) bool {
-	if _, err := os.Stat(filepath.Join(dir, cacheName)); err == os.ErrNotExist {
+	if _, err := os.Stat(filepath.Join(dir, cacheName)); os.IsNotExist(err) {
 		return false
 	} else {
 		return true
@@ -92,7 +114,8 @@ func IsCachePresent(dir string) bool {
 }
 
 func MSAuth(quick bool) (MSAuthHandle, error) {
-	publicClientApp, err := public.New(msalClientId, public.WithAuthority("https://login.microsoftonline.com/consumers"), public.WithCache(cacheAccessor))
+	c, e := current()
+	publicClientApp, err := public.New(msalClientId, public.WithAuthority(e.Authority), public.WithCache(cacheAccessor), public.WithHTTPClient(c))
 
 	var userAccount public.Account
 	var accessToken string
@@ -162,13 +185,14 @@ func getMCToken(accessToken string) (string, error) {
 
 	encoded, _ := json.Marshal(data)
 
-	request, _ := http.NewRequest("POST", "https://user.auth.xboxlive.com/user/authenticate", bytes.NewReader(encoded))
+	c, e := current()
+	request, _ := http.NewRequest("POST", e.XboxUser+"/user/authenticate", bytes.NewReader(encoded))
 
 	request.Header.Set("Content-Type", "application/json")
 	request.Header.Set("Accept", "application/json")
 	request.Header.Set("x-xbl-contract-version", "1")
 
-	response, err := http.DefaultClient.Do(request)
+	response, err := c.Do(request)
 	if err != nil {
 		return "", errors.Errorf("failed to authenticate with Xbox Live: %s", err)
 	} else {
@@ -192,12 +216,12 @@ func getMCToken(accessToken string) (string, error) {
 
 		encoded, _ := json.Marshal(body)
 
-		request, _ := http.NewRequest("POST", "https://xsts.auth.xboxlive.com/xsts/authorize", bytes.NewReader(encoded))
+		request, _ := http.NewRequest("POST", e.XboxXSTS+"/xsts/authorize", bytes.NewReader(encoded))
 		request.Header.Set("Content-Type", "application/json")
 		request.Header.Set("Accept", "application/json")
 		request.Header.Set("x-xbl-contract-version", "1")
 
-		response, err = http.DefaultClient.Do(request)
+		response, err = c.Do(request)
 
 		if err != nil {
 			return "", errors.Errorf("failed to authenticate with Xbox Live security: %s", err)
@@ -222,8 +246,9 @@ func fetchMCToken(xstx string, uhs string) string {
 	body["ensureLegacyEnabled"] = true
 	encoded, _ := json.Marshal(body)
 
-	request, _ := http.NewRequest("POST", "https://api.minecraftservices.com/authentication/login_with_xbox", bytes.NewReader(encoded))
-	response, _ := http.DefaultClient.Do(request)
+	c, e := current()
+	request, _ := http.NewRequest("POST", e.Minecraft+"/authentication/login_with_xbox", bytes.NewReader(encoded))
+	response, _ := c.Do(request)
 
 	jsonResponse, _ := ioutil.ReadAll(response.Body)
 	var data map[string]interface{}
@@ -233,3 +258,11 @@ func fetchMCToken(xstx string, uhs string) string {
 }
 
 // i have no idea what ive done, but im glad it works
+
+/* PRIVATE REGION */
+
+func current() (*http.Client, network.Endpoints) {
+	lock.RLock()
+	defer lock.RUnlock()
+	return client, endpoints
+}
@@ -15,8 +15,10 @@ import (
 	"launcher/manager"
 	"launcher/manager/comp"
 	"launcher/memory"
+	"launcher/network"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
@@ -25,7 +27,10 @@ type Bridge struct {
 	profile  microsoft.MinecraftProfile
 	gameInfo GameInfo
 	progress events.ProgressUpdateEventPayload
+	lock     sync.Mutex // guards progress, which is updated from download workers, and cancel
 	settings manager.LauncherClientSettings
+	cancel   context.CancelFunc // cancels the running install, nil when none
+	installs sync.WaitGroup
 }
 
 type ProfileInfo struct {
@@ -39,7 +44,9 @@ type HardwareInfo struct {
 }
 
 type GameInfo struct {
-	IsInstalled bool `json:"isInstalled"`
+	IsInstalled  bool     `json:"isInstalled"`
+	IsIncomplete bool     `json:"isIncomplete"` // an install was interrupted, InstallGame resumes it
+	PendingSteps []string `json:"pendingSteps"` // steps the interrupted install has left
 }
 
 type WardrobeData struct {
@@ -71,6 +78,7 @@ func InitBridge() (*Bridge, error) {
 			fmt.Println("eyo2")
 		} else {
 			bridge.settings = settings
+			_ = bridge.applySettings() // failures are logged, the defaults stay in place
 		}
 	} else {
 		logging.Logger.Warning("failed to read launcher_config.json: " + err.Error())
@@ -79,6 +87,8 @@ func InitBridge() (*Bridge, error) {
 }
 
 func (a *Bridge) TerminateBridge(ctx context.Context) bool {
+	a.CancelInstall()
+	a.installs.Wait() // let the install remove the files it was writing
 	b, _ := json.Marshal(a.settings)
 	err := ioutil.WriteFile(filepath.Join(comp.GetLauncherRoot(), "launcher_config.json"), b, os.ModePerm)
 	if err != nil {
@@ -105,14 +115,25 @@ func (a *Bridge) IsAuthenticated() bool {
 
 // GetProgress returns the progress, -1 when none
 func (a *Bridge) GetProgress() float64 {
+	a.lock.Lock()
+	defer a.lock.Unlock()
 	return a.progress.Progress
 }
 
 // GetProgressMessage returns the progress message, empty when none
 func (a *Bridge) GetProgressMessage() string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
 	return a.progress.Message
 }
 
+// GetProgressInfo returns the progress with its stage, byte counts, throughput and ETA
+func (a *Bridge) GetProgressInfo() events.ProgressUpdateEventPayload {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.progress
+}
+
 // GetWalletData returns wallet data
 func (a *Bridge) GetWalletData() string {
 	//TODO: Get from file
@@ -173,33 +194,168 @@ func (a *Bridge) GetCurrentSettings() manager.LauncherClientSettings {
 	return a.settings
 }
 
-// InstallGame installs the game, can be used for reinstall, use GetProgress to monitor
+// InstallGame installs the game, can be used for reinstall, use GetProgress to monitor and CancelInstall to stop
 func (a *Bridge) InstallGame() error {
-	events.ProgressUpdateEvent.Trigger(events.ProgressUpdateEventPayload{Progress: 0, Message: "Creating profile"})
-	err := manager.CreateProfile("latest")
+	ctx, err := a.beginInstall()
+	if err != nil {
+		return err
+	}
+	defer a.endInstall()
+
+	version := a.settings.Version
+	if version == "" {
+		mf, err := manager.GetManifest()
+		if err != nil {
+			logging.Logger.Error("Failed to fetch manifest, caused by: " + err.Error())
+			return errors.WithMessage(err, "failed to fetch manifest")
+		}
+		version = mf.Latest.Release
+	}
+	err = manager.CreateProfile(ctx, version, a.settings.Loader)
+	a.settings.Version = version // the launch selects the installed version, not a newer latest release
+	a.refreshGameInfo()
+	cancelled := errors.Is(err, context.Canceled)
+	events.ProgressUpdateEvent.Trigger(events.ProgressUpdateEventPayload{Progress: -1, ETA: -1, Cancelled: cancelled})
+	if cancelled {
+		logging.Logger.Info("Install cancelled")
+		return errors.New("install cancelled")
+	}
 	if err != nil {
 		logging.Logger.Error("Failed to create profile, caused by: " + err.Error())
 		return errors.WithMessage(err, "failed to create profile")
 	}
-	games := manager.Explore()
-	events.ProgressUpdateEvent.Trigger(events.ProgressUpdateEventPayload{Progress: 90, Message: "Installing Minecraft"})
-	err = games[0].InstallMinecraft()
+	return nil
+}
+
+// RepairGame verifies the selected profile and downloads its missing or corrupt files again, use GetProgress to monitor
+func (a *Bridge) RepairGame() (manager.VerifyReport, error) {
+	game, err := a.selectedGame()
+	if err != nil {
+		return manager.VerifyReport{}, err
+	}
+	ctx, err := a.beginInstall()
+	if err != nil {
+		return manager.VerifyReport{}, err
+	}
+	defer a.endInstall()
+
+	report, err := game.Repair(ctx)
+	cancelled := errors.Is(err, context.Canceled)
+	events.ProgressUpdateEvent.Trigger(events.ProgressUpdateEventPayload{Progress: -1, ETA: -1, Cancelled: cancelled})
+	if err != nil {
+		logging.Logger.Error("Failed to repair profile " + game.Name + ", caused by: " + err.Error())
+		return report, errors.WithMessage(err, "failed to repair game")
+	}
+	return report, nil
+}
+
+// GetLoaderVersions returns the loader builds available for the selected profile, newest first
+func (a *Bridge) GetLoaderVersions() ([]string, error) {
+	game, err := a.selectedGame()
+	if err != nil {
+		return nil, err
+	}
+	if game.Loader == "" {
+		return nil, errors.New("selected profile has no loader")
+	}
+	builds, err := manager.GetLoaderVersions(context.Background(), game.Loader, game.Version.GetMinecraftVersion())
+	if err != nil {
+		logging.Logger.Error("Failed to list loader versions, caused by: " + err.Error())
+		return nil, errors.WithMessage(err, "failed to list loader versions")
+	}
+	return builds, nil
+}
+
+// ChangeLoaderVersion upgrades or downgrades the loader of the selected profile to build, keeping its mods, saves
+// and configuration, use GetProgress to monitor and CancelInstall to stop
+func (a *Bridge) ChangeLoaderVersion(build string) error {
+	game, err := a.selectedGame()
 	if err != nil {
-		logging.Logger.Error("Failed to install minecraft, caused by: " + err.Error())
-		return errors.WithMessage(err, "failed to install native minecraft client")
+		return err
+	}
+	ctx, err := a.beginInstall()
+	if err != nil {
+		return err
+	}
+	defer a.endInstall()
+
+	name := game.Name
+	err = game.ChangeLoader(ctx, build)
+	cancelled := errors.Is(err, context.Canceled)
+	events.ProgressUpdateEvent.Trigger(events.ProgressUpdateEventPayload{Progress: -1, ETA: -1, Cancelled: cancelled})
+	if err != nil {
+		logging.Logger.Error("Failed to change loader of profile " + name + ", caused by: " + err.Error())
+		return errors.WithMessage(err, "failed to change loader")
+	}
+	a.settings.RenameProfile(name, game.Name)
+	return nil
+}
+
+// CollectGarbage removes the files no installed version uses, a dry run only reports them
+func (a *Bridge) CollectGarbage(dryRun bool) (manager.GCReport, error) {
+	_, err := a.beginInstall() // never collect files an install is downloading
+	if err != nil {
+		return manager.GCReport{}, err
+	}
+	defer a.endInstall()
+
+	report, err := manager.CollectGarbage(dryRun)
+	if err != nil {
+		logging.Logger.Error("Failed to collect garbage, caused by: " + err.Error())
+		return report, errors.WithMessage(err, "failed to collect garbage")
+	}
+	logging.Logger.Info(fmt.Sprintf("Collected %d files, %d bytes", len(report.Files), report.Bytes))
+	return report, nil
+}
+
+// ImportMinecraft imports the game files of an existing minecraft directory, the vanilla launcher's when dir is empty.
+// With link set, files are hardlinked instead of copied where possible. Use CancelInstall to stop.
+func (a *Bridge) ImportMinecraft(dir string, link bool) (manager.ImportReport, error) {
+	if dir == "" {
+		dir = comp.GetMinecraftDir()
+	}
+	ctx, err := a.beginInstall()
+	if err != nil {
+		return manager.ImportReport{}, err
+	}
+	defer a.endInstall()
+
+	report, err := manager.ImportMinecraft(ctx, dir, link)
+	if err != nil {
+		logging.Logger.Error("Failed to import " + dir + ", caused by: " + err.Error())
+		return report, errors.WithMessage(err, "failed to import game files")
+	}
+	logging.Logger.Info(fmt.Sprintf("Imported %d files, %d bytes from %s", report.Imported, report.Bytes, dir))
+	return report, nil
+}
+
+// CancelInstall stops the running install or repair, returns false when none is running
+func (a *Bridge) CancelInstall() bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.cancel == nil {
+		return false
 	}
-	events.ProgressUpdateEvent.Trigger(events.ProgressUpdateEventPayload{Progress: 100, Message: "Finishing up"})
-	events.ProgressUpdateEvent.Trigger(events.ProgressUpdateEventPayload{Progress: -1})
-	return err
+	a.cancel()
+	return true
 }
 
 // LaunchGame launches the game, use GetProgress to monitor
 func (a *Bridge) LaunchGame() error {
 	if a.profile.AccessToken != "" {
+		game, err := a.selectedGame()
+		if err != nil {
+			return err
+		}
+		// the repair before the launch downloads files like any install
+		ctx, err := a.beginInstall()
+		if err != nil {
+			return err
+		}
+		defer a.endInstall()
 		runtime.WindowHide(a.ctx)
-		games := manager.Explore()
 
-		err := games[0].Launch(manager.LauncherAuth{
+		err = game.Launch(ctx, manager.LauncherAuth{
 			Username:    a.profile.Name,
 			AccessToken: a.profile.AccessToken,
 			UUID:        a.profile.ID,
@@ -217,14 +373,151 @@ func (a *Bridge) LaunchGame() error {
 	}
 }
 
-func (a *Bridge) SetClientSettings(settings manager.LauncherClientSettings) {
+// GetVersions returns the available versions of the given types (release, snapshot, old_beta, old_alpha), newest first
+func (a *Bridge) GetVersions(types []string) ([]manager.VersionInfo, error) {
+	versions, err := manager.ListVersions(types...)
+	if err != nil {
+		logging.Logger.Error("Failed to list versions, caused by: " + err.Error())
+		return nil, errors.WithMessage(err, "failed to list versions")
+	}
+	return versions, nil
+}
+
+// SelectVersion selects the version installed and launched by InstallGame and LaunchGame
+func (a *Bridge) SelectVersion(id string) error {
+	mf, err := manager.GetManifest()
+	if err != nil {
+		return errors.WithMessage(err, "failed to fetch manifest")
+	}
+	for _, v := range mf.Versions {
+		if v.ID == id {
+			a.settings.Version = id
+			return nil
+		}
+	}
+	return errors.New("unknown version " + id)
+}
+
+// SelectLoader selects the mod loader, fabric, quilt, forge or neoforge, installed by InstallGame and launched by LaunchGame
+func (a *Bridge) SelectLoader(kind string) error {
+	switch kind {
+	case manager.LoaderFabric, manager.LoaderQuilt, manager.LoaderForge, manager.LoaderNeoForge:
+		a.settings.Loader = kind
+		return nil
+	}
+	return errors.New("unknown loader " + kind)
+}
+
+// GetJavaInstallations returns the java installations found on the machine
+func (a *Bridge) GetJavaInstallations() []manager.JavaInstallation {
+	return manager.DiscoverJava()
+}
+
+// SetProfileJava pins the java installation in home for the profile, an empty home restores the managed runtime
+func (a *Bridge) SetProfileJava(profile string, home string) error {
+	if home == "" {
+		delete(a.settings.JavaHomes, profile)
+		return nil
+	}
+	if _, err := manager.InspectJava(home); err != nil {
+		return errors.WithMessage(err, "invalid java installation")
+	}
+	if a.settings.JavaHomes == nil {
+		a.settings.JavaHomes = make(map[string]string)
+	}
+	a.settings.JavaHomes[profile] = home
+	return nil
+}
+
+func (a *Bridge) SetClientSettings(settings manager.LauncherClientSettings) error {
 	a.settings = settings
+	return a.applySettings()
 }
 
 /* JS API END */
 
 /* PRIVATE REGION */
 
+// applySettings configures the mirrors and the http client of the api and manager packages from the settings
+func (a *Bridge) applySettings() error {
+	manager.SetMirrors(a.settings.Mirrors)
+	cfg := a.settings.Network
+	for _, configure := range []func(network.Config) error{manager.Configure, microsoft.Configure, backend.Configure} {
+		if err := configure(cfg); err != nil {
+			logging.Logger.Error("Failed to configure network, caused by: " + err.Error())
+			return errors.WithMessage(err, "invalid network settings")
+		}
+	}
+	return nil
+}
+
+// beginInstall returns the context of a new install, failing when one is already running
+func (a *Bridge) beginInstall() (context.Context, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.cancel != nil {
+		return nil, errors.New("an install is already running")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+	a.installs.Add(1)
+	return ctx, nil
+}
+
+func (a *Bridge) endInstall() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.cancel()
+	a.cancel = nil
+	a.installs.Done()
+}
+
+// refreshGameInfo updates the installation state from the install journal and the installed profiles.
+// Installs made before the journal existed have none, so finding a profile is enough for them.
+func (a *Bridge) refreshGameInfo() {
+	journal, err := manager.ReadInstallJournal()
+	incomplete := err == nil && !journal.Completed
+	a.gameInfo = GameInfo{
+		IsInstalled:  !incomplete && len(manager.Explore()) > 0,
+		IsIncomplete: incomplete,
+	}
+	if incomplete {
+		a.gameInfo.PendingSteps = journal.PendingSteps()
+	}
+}
+
+// selectedGame returns the installed profile of the configured version, any version when none is configured.
+// The profile of the selected loader is preferred over other loader profiles, which are preferred over vanilla ones.
+func (a *Bridge) selectedGame() (manager.LauncherProfile, error) {
+	games := manager.Explore()
+	if len(games) == 0 {
+		return manager.LauncherProfile{}, errors.New("no installed profile found")
+	}
+	loader := a.settings.Loader
+	if loader == "" {
+		loader = manager.LoaderFabric
+	}
+	rank := func(game manager.LauncherProfile) int {
+		r := 0
+		if a.settings.Version == "" || game.Version.GetMinecraftVersion() == a.settings.Version {
+			r += 4
+		}
+		if game.Loader == loader {
+			r += 2
+		} else if game.Loader != "" {
+			r++
+		}
+		return r
+	}
+	selected := games[0]
+	for _, game := range games[1:] {
+		if rank(game) > rank(selected) {
+			selected = game
+		}
+	}
+	return selected, nil
+}
+
 func (a *Bridge) getProfile(handle microsoft.MSAuthHandle) (ProfileInfo, error) {
 	profile, err := handle.GetMinecraftProfile()
 	if err != nil {
@@ -243,7 +536,7 @@ func (a *Bridge) Startup(ctx context.Context) {
 	// Perform your setup here
 	_ = os.Chdir(comp.GetLauncherRoot())
 	a.ctx = ctx
-	a.gameInfo.IsInstalled = len(manager.Explore()) > 0
+	a.refreshGameInfo()
 	progressHandler := progressUpdatedNotifier{
 		a,
 	}
@@ -255,5 +548,7 @@ type progressUpdatedNotifier struct {
 }
 
 func (p progressUpdatedNotifier) Handle(payload events.ProgressUpdateEventPayload) {
+	p.b.lock.Lock()
+	defer p.b.lock.Unlock()
 	p.b.progress = payload
 }
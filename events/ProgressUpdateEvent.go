@@ -3,8 +3,14 @@ package events
 var ProgressUpdateEvent progressUpdateEvent
 
 type ProgressUpdateEventPayload struct {
-	Progress float64
-	Message  string
+	Progress   float64 // percentage of BytesTotal done, -1 when idle
+	Message    string
+	Stage      string  // the install step running, see the Stage constants of the manager package
+	BytesDone  int64   // bytes downloaded or verified so far
+	BytesTotal int64   // bytes expected, grows while the install discovers files of unknown size
+	Speed      float64 // bytes per second over the last seconds
+	ETA        float64 // seconds remaining, -1 when unknown
+	Cancelled  bool    // the install was cancelled, sent along with a Progress of -1
 }
 
 type progressUpdateEvent struct {
@@ -0,0 +1,66 @@
+package manager
+
+import (
+	"io"
+	"launcher/manager/comp"
+	"os"
+	"path/filepath"
+)
+
+// GetObjectPath returns the path of the asset in the content addressed object store
+func (a *Asset) GetObjectPath() string {
+	return filepath.Join(comp.GetObjectsPath(), a.Hash[0:2], a.Hash)
+}
+
+// GetGameAssetsPath returns the directory the game reads its assets from, substituted for ${game_assets}
+func (i *AssetIndex) GetGameAssetsPath(id string, gameDir string) string {
+	if i.MapToResources {
+		return filepath.Join(gameDir, "resources")
+	}
+	if i.Virtual {
+		return filepath.Join(comp.GetVirtualAssetsPath(), id)
+	}
+	return comp.GetAssetsPath()
+}
+
+// Materialize copies the downloaded objects into the legacy layout the index requires,
+// indexes using the object store layout are left untouched
+func (i *AssetIndex) Materialize(id string, gameDir string) error {
+	if !i.Virtual && !i.MapToResources {
+		return nil
+	}
+	dir := i.GetGameAssetsPath(id, gameDir)
+	for name, asset := range i.Objects {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if s, err := os.Stat(target); err == nil && s.Size() == asset.Size {
+			continue
+		}
+		err := copyFile(asset.GetObjectPath(), target)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/* PRIVATE REGION */
+
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	err = os.MkdirAll(filepath.Dir(dst), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, in)
+	return err
+}
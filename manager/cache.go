@@ -0,0 +1,157 @@
+package manager
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"launcher/manager/comp"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// cacheEntry describes a cached metadata document, stored next to the document itself
+type cacheEntry struct {
+	Url          string `json:"url"`
+	ETag         string `json:"etag"`
+	LastModified string `json:"last_modified"`
+}
+
+// fetchMetadata returns the document at address, revalidating the on-disk copy when one exists.
+// When hash is set, a cached copy matching it is returned without contacting the server.
+// When the server is unreachable, the cached copy is used instead.
+func fetchMetadata(ctx context.Context, address string, hash string) ([]byte, error) {
+	cached, entry, cacheErr := readCache(address)
+	if cacheErr == nil && hash != "" {
+		if sha1Hex(cached) == hash {
+			return cached, nil
+		}
+		// a corrupt copy must not be revalidated, the server would confirm it as unmodified
+		removeCache(address)
+		cacheErr = errors.New("cached copy of " + address + " is corrupt")
+	}
+
+	fallback := func(cause error) ([]byte, error) {
+		if cacheErr != nil {
+			return nil, cause
+		}
+		return cached, nil
+	}
+
+	var b []byte
+	var header http.Header
+	notModified := false
+	err := withRetry(ctx, address, func(candidate string) error {
+		req, err := http.NewRequestWithContext(ctx, "GET", candidate, nil)
+		if err != nil {
+			return err
+		}
+		if cacheErr == nil {
+			if entry.ETag != "" {
+				req.Header.Set("If-None-Match", entry.ETag)
+			}
+			if entry.LastModified != "" {
+				req.Header.Set("If-Modified-Since", entry.LastModified)
+			}
+		}
+
+		r, err := httpClient().Do(req)
+		if err != nil {
+			return err
+		}
+		defer r.Body.Close()
+
+		if r.StatusCode == http.StatusNotModified && cacheErr == nil {
+			notModified = true
+			return nil
+		}
+		if r.StatusCode != http.StatusOK {
+			return newStatusError(r, candidate)
+		}
+		b, err = io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		if hash != "" && sha1Hex(b) != hash {
+			return errors.New("checksum mismatch of " + candidate)
+		}
+		header = r.Header
+		return nil
+	})
+	if err != nil {
+		return fallback(err)
+	}
+	if notModified {
+		return fallback(errors.New("server reported an unmodified document"))
+	}
+
+	err = writeCache(address, b, cacheEntry{
+		Url:          address,
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to cache "+address)
+	}
+	return b, nil
+}
+
+func cacheFile(address string) string {
+	return filepath.Join(comp.GetCachePath(), "meta", sha1Hex([]byte(address)))
+}
+
+func readCache(address string) ([]byte, cacheEntry, error) {
+	b, err := os.ReadFile(cacheFile(address) + ".json")
+	if err != nil {
+		return nil, cacheEntry{}, err
+	}
+	// the metadata is written last, without it the document may be from an interrupted write
+	var entry cacheEntry
+	m, err := os.ReadFile(cacheFile(address) + ".meta")
+	if err == nil {
+		err = json.Unmarshal(m, &entry)
+	}
+	if err != nil {
+		removeCache(address)
+		return nil, cacheEntry{}, err
+	}
+	return b, entry, nil
+}
+
+func removeCache(address string) {
+	_ = os.Remove(cacheFile(address) + ".json")
+	_ = os.Remove(cacheFile(address) + ".meta")
+}
+
+func writeCache(address string, b []byte, entry cacheEntry) error {
+	err := os.MkdirAll(filepath.Dir(cacheFile(address)), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	m, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_ = os.Remove(cacheFile(address) + ".meta") // the document is dropped if the write is interrupted
+	err = writeCacheFile(cacheFile(address)+".json", b)
+	if err != nil {
+		return err
+	}
+	return writeCacheFile(cacheFile(address)+".meta", m)
+}
+
+// writeCacheFile writes to a temporary file first, so that a crash never leaves a truncated copy
+func writeCacheFile(path string, b []byte) error {
+	err := os.WriteFile(path+".tmp", b, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func sha1Hex(b []byte) string {
+	return fmt.Sprintf("%x", sha1.Sum(b))
+}
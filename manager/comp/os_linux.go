@@ -0,0 +1,31 @@
+//go:build linux
+
+package comp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func GetOSName() string {
+	return "linux"
+}
+
+func GetOSVersion() string {
+	b, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// GetJavaExecutable returns the java executable of the runtime installed in dir
+func GetJavaExecutable(dir string) string {
+	return filepath.Join(dir, "bin", "java")
+}
+
+// GetJavaSearchPaths returns the directories system java installations are usually found in
+func GetJavaSearchPaths() []string {
+	return []string{"/usr/lib/jvm", "/usr/lib64/jvm", "/usr/java", "/opt/java"}
+}
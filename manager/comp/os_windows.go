@@ -0,0 +1,54 @@
+//go:build windows
+
+package comp
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+type osVersionInfo struct {
+	size         uint32
+	majorVersion uint32
+	minorVersion uint32
+	buildNumber  uint32
+	platformId   uint32
+	csdVersion   [128]uint16
+}
+
+func GetOSName() string {
+	return "windows"
+}
+
+func GetOSVersion() string {
+	var mod = syscall.NewLazyDLL("ntdll.dll")
+	var proc = mod.NewProc("RtlGetVersion")
+	info := osVersionInfo{}
+	info.size = uint32(unsafe.Sizeof(info))
+
+	ret, _, _ := proc.Call(uintptr(unsafe.Pointer(&info)))
+	if ret != 0 {
+		return ""
+	}
+	return strconv.Itoa(int(info.majorVersion)) + "." + strconv.Itoa(int(info.minorVersion))
+}
+
+// GetJavaExecutable returns the java executable of the runtime installed in dir
+func GetJavaExecutable(dir string) string {
+	return filepath.Join(dir, "bin", "javaw.exe")
+}
+
+// GetJavaSearchPaths returns the directories system java installations are usually found in
+func GetJavaSearchPaths() []string {
+	programFiles := os.Getenv("ProgramFiles")
+	return []string{
+		filepath.Join(programFiles, "Java"),
+		filepath.Join(programFiles, "Eclipse Adoptium"),
+		filepath.Join(programFiles, "Microsoft"),
+		filepath.Join(programFiles, "Zulu"),
+		filepath.Join(programFiles, "BellSoft"),
+	}
+}
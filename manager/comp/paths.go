@@ -10,6 +10,10 @@ func GetAssetsPath() string {
 	return filepath.Join(GetLauncherRoot(), "assets")
 }
 
+func GetObjectsPath() string {
+	return filepath.Join(GetAssetsPath(), "objects")
+}
+
 func GetLogCfgsPath() string {
 	return filepath.Join(GetAssetsPath(), "log_cfgs")
 }
@@ -17,3 +21,27 @@ func GetLogCfgsPath() string {
 func GetIndexesPath() string {
 	return filepath.Join(GetAssetsPath(), "indexes")
 }
+
+func GetCachePath() string {
+	return filepath.Join(GetLauncherRoot(), "cache")
+}
+
+func GetNativesPath() string {
+	return filepath.Join(GetLauncherRoot(), "natives")
+}
+
+func GetVirtualAssetsPath() string {
+	return filepath.Join(GetAssetsPath(), "virtual")
+}
+
+func GetRuntimesPath() string {
+	return filepath.Join(GetLauncherRoot(), "runtime")
+}
+
+func GetInstallJournalPath() string {
+	return filepath.Join(GetLauncherRoot(), "install_journal.json")
+}
+
+func GetStoreIndexPath() string {
+	return filepath.Join(GetLauncherRoot(), "store_index.json")
+}
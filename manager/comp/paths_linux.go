@@ -11,3 +11,9 @@ func GetLauncherRoot() string {
 	path, _ := os.UserHomeDir() // By writing it like this, i place my faith in user to not run this on a system without the HOME variable
 	return filepath.Join(path, ".genecraft")
 }
+
+// GetMinecraftDir returns the directory of the vanilla launcher
+func GetMinecraftDir() string {
+	path, _ := os.UserHomeDir()
+	return filepath.Join(path, ".minecraft")
+}
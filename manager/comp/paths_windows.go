@@ -10,3 +10,8 @@ import (
 func GetLauncherRoot() string {
 	return filepath.Join(os.Getenv("APPDATA"), ".genecraft")
 }
+
+// GetMinecraftDir returns the directory of the vanilla launcher
+func GetMinecraftDir() string {
+	return filepath.Join(os.Getenv("APPDATA"), ".minecraft")
+}
@@ -0,0 +1,148 @@
+package manager
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"github.com/pkg/errors"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// partSuffix marks files still being downloaded
+const partSuffix = ".part"
+
+// downloadFile streams address into path. The body is written to a .part file which is resumed with a range
+// request after an interruption, and renamed into place only once it matches size and hash.
+// An empty hash or a zero size skip the respective check. Mirrors are tried first and failures are retried.
+// When ctx is cancelled the part file is removed.
+func downloadFile(ctx context.Context, address string, path string, hash string, size int64) error {
+	return downloadVerifiedFile(ctx, address, path, sha1.New, hash, size)
+}
+
+/* PRIVATE REGION */
+
+// downloadVerifiedFile is downloadFile with the hash computed by newHash, like the sha256 of maven checksums
+func downloadVerifiedFile(ctx context.Context, address string, path string, newHash func() hash.Hash, sum string, size int64) error {
+	var counted int64 // bytes of the file reported as done, across attempts
+	err := withRetry(ctx, address, func(address string) error {
+		return downloadFileOnce(ctx, address, path, newHash, sum, size, &counted)
+	})
+	if err != nil {
+		installProgress.writer(size == 0).advance(-counted)
+	}
+	if ctx.Err() != nil {
+		_ = os.Remove(path + partSuffix)
+	}
+	return err
+}
+
+// fetchFile returns the small file at address, like a checksum file, through the mirrors and retries of downloads
+func fetchFile(ctx context.Context, address string) ([]byte, error) {
+	var b []byte
+	err := withRetry(ctx, address, func(address string) error {
+		req, err := http.NewRequestWithContext(ctx, "GET", address, nil)
+		if err != nil {
+			return err
+		}
+		r, err := httpClient().Do(req)
+		if err != nil {
+			return err
+		}
+		defer r.Body.Close()
+		if r.StatusCode != http.StatusOK {
+			return newStatusError(r, address)
+		}
+		b, err = io.ReadAll(r.Body)
+		return err
+	})
+	return b, err
+}
+
+// downloadFileOnce makes one attempt of downloadVerifiedFile. counted holds the bytes of the file already reported
+// as done by earlier attempts, the progress is corrected by the bytes they wrote that this attempt starts over.
+func downloadFileOnce(ctx context.Context, address string, path string, newHash func() hash.Hash, sum string, size int64, counted *int64) error {
+	part := path + partSuffix
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	h := newHash()
+	var offset int64
+	if s, err := os.Stat(part); err == nil && (size == 0 || s.Size() < size) {
+		offset, err = hashFile(part, h)
+		if err != nil {
+			offset = 0
+			h.Reset()
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", address, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+	r, err := httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	switch {
+	case r.StatusCode == http.StatusPartialContent && offset > 0:
+	case r.StatusCode >= 200 && r.StatusCode < 300:
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC // the server ignored the range, start over
+		offset = 0
+		h.Reset()
+	default:
+		if r.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			_ = os.Remove(part) // the part file is corrupt, the next attempt starts over
+		}
+		return newStatusError(r, address)
+	}
+
+	f, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return err
+	}
+	counter := installProgress.writer(size == 0)
+	counter.advance(offset - *counted) // a part left by an earlier run counts as done, a discarded one no more
+	written, err := io.Copy(io.MultiWriter(f, h, counter), r.Body)
+	*counted = offset + written
+	closeErr := f.Close()
+	if err != nil {
+		return err // the part file is kept for resuming
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	total := offset + written
+	if size > 0 && total != size {
+		_ = os.Remove(part)
+		return errors.Errorf("size mismatch of %s, expected %d bytes, got %d", address, size, total)
+	}
+	if sum != "" && fmt.Sprintf("%x", h.Sum(nil)) != sum {
+		_ = os.Remove(part)
+		return errors.New("checksum mismatch of " + address)
+	}
+	return os.Rename(part, path)
+}
+
+// hashFile feeds the file into h and returns its size
+func hashFile(path string, h hash.Hash) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return io.Copy(h, f)
+}
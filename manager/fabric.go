@@ -0,0 +1,63 @@
+package manager
+
+import (
+	"context"
+	"github.com/pkg/errors"
+)
+
+// FabricLoaderVersion is a fabric loader build available for a minecraft version
+type FabricLoaderVersion struct {
+	Loader struct {
+		Version string `json:"version"`
+		Stable  bool   `json:"stable"`
+		Maven   string `json:"maven"`
+	} `json:"loader"`
+	Intermediary struct {
+		Version string `json:"version"`
+		Maven   string `json:"maven"`
+	} `json:"intermediary"`
+}
+
+// GetFabricLoaders returns the fabric loader builds available for the minecraft version, newest first
+func GetFabricLoaders(ctx context.Context, version string) ([]FabricLoaderVersion, error) {
+	var loaders []FabricLoaderVersion
+	err := receiveJSONObject(ctx, fabricLoadersUrl(version), &loaders)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to fetch fabric loader versions")
+	}
+	if len(loaders) == 0 {
+		return nil, errors.Errorf("fabric does not support minecraft %s", version)
+	}
+	return loaders, nil
+}
+
+/* PRIVATE REGION */
+
+// installFabric writes the profile of the fabric loader for the minecraft version and returns its id.
+// An empty loader selects the newest stable build. The libraries of the profile are installed with the others.
+func installFabric(ctx context.Context, version string, loader string) (string, error) {
+	if loader == "" {
+		loaders, err := GetFabricLoaders(ctx, version)
+		if err != nil {
+			return "", err
+		}
+		loader = loaders[0].Loader.Version
+		for _, l := range loaders {
+			if l.Loader.Stable {
+				loader = l.Loader.Version
+				break
+			}
+		}
+	}
+
+	address := fabricProfileUrl(version, loader)
+	b, err := fetchMetadata(ctx, address, "")
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to fetch fabric profile")
+	}
+	id, err := writeLoaderProfile(b, version)
+	if err != nil {
+		removeCache(address)
+	}
+	return id, err
+}
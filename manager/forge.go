@@ -0,0 +1,500 @@
+package manager
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"launcher/logging"
+	"launcher/manager/comp"
+	"launcher/manager/maven"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GetForgeVersions returns the forge builds available for the minecraft version, newest first.
+// Builds are named like their maven version, <minecraft>-<forge>.
+func GetForgeVersions(ctx context.Context, version string) ([]string, error) {
+	builds, err := receiveMavenVersions(ctx, forgeMetadataUrl(), func(build string) bool {
+		return strings.HasPrefix(build, version+"-")
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to fetch forge versions")
+	}
+	if len(builds) == 0 {
+		return nil, errors.Errorf("forge does not support minecraft %s", version)
+	}
+	return builds, nil
+}
+
+// GetNeoForgeVersions returns the neoforge builds available for the minecraft version, newest first.
+// Neoforge builds drop the leading 1 of the minecraft version, 1.20.4 is built as 20.4.x.
+func GetNeoForgeVersions(ctx context.Context, version string) ([]string, error) {
+	parts := strings.Split(version, ".")
+	valid := len(parts) == 2 || len(parts) == 3
+	for _, part := range parts {
+		_, err := strconv.Atoi(part)
+		valid = valid && err == nil
+	}
+	if !valid || parts[0] != "1" {
+		return nil, errors.Errorf("neoforge does not support version %s", version)
+	}
+	parts = append(parts, "0")
+	prefix := parts[1] + "." + parts[2] + "."
+	builds, err := receiveMavenVersions(ctx, neoForgeMetadataUrl(), func(build string) bool {
+		return strings.HasPrefix(build, prefix)
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to fetch neoforge versions")
+	}
+	if len(builds) == 0 {
+		return nil, errors.Errorf("neoforge does not support minecraft %s", version)
+	}
+	return builds, nil
+}
+
+/* PRIVATE REGION */
+
+// forgeInstallProfile is the install_profile.json of a forge or neoforge installer
+type forgeInstallProfile struct {
+	Spec       int                        `json:"spec"`
+	Version    string                     `json:"version"`
+	Json       string                     `json:"json"`      // installer entry of the version JSON
+	Minecraft  string                     `json:"minecraft"` // minecraft version the loader is built for
+	Data       map[string]forgeSidedValue `json:"data"`
+	Processors []forgeProcessor           `json:"processors"`
+	Libraries  []Library                  `json:"libraries"` // libraries of the processors
+}
+
+type forgeSidedValue struct {
+	Client string `json:"client"`
+	Server string `json:"server"`
+}
+
+// forgeProcessor is a java program the installer runs to produce the patched client
+type forgeProcessor struct {
+	Sides     []string          `json:"sides"` // every side when empty
+	Jar       string            `json:"jar"`
+	Classpath []string          `json:"classpath"`
+	Args      []string          `json:"args"`
+	Outputs   map[string]string `json:"outputs"` // produced file and its SHA-1, both may be placeholders
+}
+
+type mavenMetadata struct {
+	Versions []string `xml:"versioning>versions>version"`
+}
+
+// installForge installs the forge or neoforge build with the installer of the build, running its processors
+// with the managed java runtime, and returns the id of the profile. An empty build selects the newest stable one.
+func installForge(ctx context.Context, kind string, vanilla Version, build string) (string, error) {
+	if build == "" {
+		var builds []string
+		var err error
+		if kind == LoaderNeoForge {
+			builds, err = GetNeoForgeVersions(ctx, vanilla.ID)
+		} else {
+			builds, err = GetForgeVersions(ctx, vanilla.ID)
+		}
+		if err != nil {
+			return "", err
+		}
+		build = builds[0]
+		for _, b := range builds {
+			if !strings.Contains(b, "beta") {
+				build = b
+				break
+			}
+		}
+	}
+	coordinate := maven.Coordinate{Group: "net.minecraftforge", Artifact: "forge", Version: build, Classifier: "installer"}
+	repository := currentEndpoints().Forge
+	if kind == LoaderNeoForge {
+		coordinate.Group, coordinate.Artifact = "net.neoforged", "neoforge"
+		repository = currentEndpoints().NeoForge
+	}
+
+	tmp, err := os.MkdirTemp("", kind+"-installer-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+	installer := filepath.Join(tmp, "installer.jar")
+	_, err = libraryResolver().Resolve(ctx, coordinate, repository, installer)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to download "+kind+" installer")
+	}
+
+	r, err := zip.OpenReader(installer)
+	if err != nil {
+		return "", errors.WithMessage(err, "invalid "+kind+" installer")
+	}
+	defer r.Close()
+	var profile forgeInstallProfile
+	err = readZipJSON(&r.Reader, "install_profile.json", &profile)
+	if err != nil {
+		return "", errors.WithMessage(err, "invalid "+kind+" installer")
+	}
+	if profile.Spec < 1 && profile.Json == "" {
+		return "", errors.New("installers of forge for minecraft 1.12 and older are not supported")
+	}
+	if profile.Minecraft != vanilla.ID {
+		return "", errors.Errorf("%s %s is not built for minecraft %s", kind, build, vanilla.ID)
+	}
+	ver, err := readZipFile(&r.Reader, strings.TrimPrefix(profile.Json, "/"))
+	if err != nil {
+		return "", errors.WithMessage(err, "invalid "+kind+" installer")
+	}
+
+	fi := forgeInstall{ctx: ctx, installer: &r.Reader, path: installer, tmp: tmp, vanilla: vanilla}
+	err = fi.extractLibraries()
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to extract "+kind+" libraries")
+	}
+	err = fi.run(profile)
+	if err != nil {
+		return "", err
+	}
+	id, err := writeLoaderProfile(ver, vanilla.ID)
+	if err != nil {
+		return "", err
+	}
+	return id, writeInstallerOutputs(id, fi.outputs)
+}
+
+// forgeInstall holds the state of a running forge installer
+type forgeInstall struct {
+	ctx       context.Context
+	installer *zip.Reader
+	path      string // path of the installer
+	tmp       string // directory for the files the installer extracts
+	vanilla   Version
+	data      map[string]string
+	outputs   []string // files produced for the profile that its version JSON does not list
+}
+
+// extractLibraries extracts the libraries bundled in the maven directory of the installer
+func (fi *forgeInstall) extractLibraries() error {
+	dir := comp.GetLibraryPath()
+	for _, f := range fi.installer.File {
+		if f.FileInfo().IsDir() || !strings.HasPrefix(f.Name, "maven/") {
+			continue
+		}
+		target := filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(f.Name, "maven/")))
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return errors.New("illegal path in archive: " + f.Name)
+		}
+		err := extractFile(f, target+partSuffix)
+		if err != nil {
+			return err
+		}
+		err = os.Rename(target+partSuffix, target)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run downloads the processor libraries, resolves the data of the profile and runs the client processors
+func (fi *forgeInstall) run(profile forgeInstallProfile) error {
+	env := CurrentEnvironment()
+	for _, lib := range profile.Libraries {
+		for _, artifact := range lib.GetArtifacts(env) {
+			_, err := downloadLibraryArtifact(fi.ctx, lib, artifact)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// the processors patch the vanilla client, which is otherwise downloaded by the client step
+	jar := GetVersionJARPath(fi.vanilla.ID)
+	if !checkFile(jar, fi.vanilla.Downloads["client"].SHA1) {
+		err := installMinecraft(fi.ctx, jar, fi.vanilla)
+		if err != nil {
+			return errors.WithMessage(err, "failed to download minecraft client")
+		}
+	}
+
+	fi.data = map[string]string{
+		"SIDE":              "client",
+		"MINECRAFT_JAR":     jar,
+		"MINECRAFT_VERSION": fi.vanilla.ID,
+		"ROOT":              comp.GetLauncherRoot(),
+		"INSTALLER":         fi.path,
+		"LIBRARY_DIR":       comp.GetLibraryPath(),
+	}
+	for key, value := range profile.Data {
+		v, err := fi.resolveData(value.Client)
+		if err != nil {
+			return errors.WithMessage(err, "failed to resolve installer data "+key)
+		}
+		fi.data[key] = v
+		if strings.HasPrefix(value.Client, "[") {
+			fi.outputs = append(fi.outputs, v)
+		}
+	}
+
+	var processors []forgeProcessor
+	for _, p := range profile.Processors {
+		if len(p.Sides) == 0 || contains(p.Sides, "client") {
+			processors = append(processors, p)
+		}
+	}
+	for i, p := range processors {
+		installProgress.setMessage(fmt.Sprintf("Running processor %d/%d", i+1, len(processors)))
+		err := fi.runProcessor(p)
+		if err != nil {
+			return errors.WithMessage(err, "processor "+p.Jar+" failed")
+		}
+	}
+	return nil
+}
+
+// resolveData resolves a data value of the install profile: an artifact, a literal or a file of the installer
+func (fi *forgeInstall) resolveData(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+		return filepath.Join(comp.GetLibraryPath(), mavenPath(value[1:len(value)-1])), nil
+	case strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'"):
+		return value[1 : len(value)-1], nil
+	}
+	name := strings.TrimPrefix(value, "/")
+	for _, f := range fi.installer.File {
+		if f.Name == name {
+			target := filepath.Join(fi.tmp, filepath.FromSlash(name))
+			return target, extractFile(f, target)
+		}
+	}
+	return "", errors.New("installer has no file " + value)
+}
+
+// resolveArg resolves an argument of a processor: an artifact or a string with {KEY} data references
+func (fi *forgeInstall) resolveArg(arg string) (string, error) {
+	if strings.HasPrefix(arg, "[") && strings.HasSuffix(arg, "]") {
+		return filepath.Join(comp.GetLibraryPath(), mavenPath(arg[1:len(arg)-1])), nil
+	}
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(arg, '{')
+		end := strings.IndexByte(arg, '}')
+		if start < 0 || end < start {
+			b.WriteString(arg)
+			break
+		}
+		value, ok := fi.data[arg[start+1:end]]
+		if !ok {
+			return "", errors.New("unknown installer data " + arg[start:end+1])
+		}
+		b.WriteString(arg[:start])
+		b.WriteString(value)
+		arg = arg[end+1:]
+	}
+	resolved := b.String()
+	if strings.HasPrefix(resolved, "'") && strings.HasSuffix(resolved, "'") {
+		return resolved[1 : len(resolved)-1], nil // literal
+	}
+	return resolved, nil
+}
+
+// runProcessor runs the processor, unless its outputs are already present, and verifies its outputs
+func (fi *forgeInstall) runProcessor(p forgeProcessor) error {
+	outputs := make(map[string]string)
+	for file, hash := range p.Outputs {
+		path, err := fi.resolveArg(file)
+		if err != nil {
+			return err
+		}
+		outputs[path], err = fi.resolveArg(hash)
+		if err != nil {
+			return err
+		}
+		fi.outputs = append(fi.outputs, path)
+	}
+	present := len(outputs) > 0
+	for path, hash := range outputs {
+		present = present && checkSHA1Hash(path, hash)
+	}
+	if present {
+		return nil
+	}
+
+	jar := filepath.Join(comp.GetLibraryPath(), mavenPath(p.Jar))
+	mainClass, err := readMainClass(jar)
+	if err != nil {
+		return err
+	}
+	cp := []string{jar}
+	for _, name := range p.Classpath {
+		cp = append(cp, filepath.Join(comp.GetLibraryPath(), mavenPath(name)))
+	}
+	args := []string{"-cp", strings.Join(cp, string(comp.GetSeparator())), mainClass}
+	for _, arg := range p.Args {
+		a, err := fi.resolveArg(arg)
+		if err != nil {
+			return err
+		}
+		args = append(args, a)
+	}
+
+	java := comp.GetJavaExecutable(GetJavaRuntimePath(fi.vanilla.GetJavaVersion().Component))
+	cmd := exec.CommandContext(fi.ctx, java, args...)
+	cmd.Dir = fi.tmp
+	out, err := cmd.CombinedOutput()
+	if fi.ctx.Err() != nil {
+		return fi.ctx.Err()
+	}
+	if err != nil {
+		logging.Logger.Error(string(out))
+		return err
+	}
+
+	for path, hash := range outputs {
+		if !checkSHA1Hash(path, hash) {
+			_ = os.Remove(path)
+			return errors.New("output " + path + " does not match its hash")
+		}
+	}
+	return nil
+}
+
+// getInstallerOutputsPath returns the path of the list of the files the installer produced for the profile
+func getInstallerOutputsPath(id string) string {
+	return filepath.Join(filepath.Dir(GetVersionFilePath(id)), "installer_outputs.json")
+}
+
+// writeInstallerOutputs records the files the installer produced for the profile, so that they are not collected
+func writeInstallerOutputs(id string, outputs []string) error {
+	paths := []string{}
+	seen := make(map[string]bool)
+	for _, output := range outputs {
+		rel, err := filepath.Rel(comp.GetLauncherRoot(), output)
+		if err != nil || strings.HasPrefix(rel, "..") || seen[rel] {
+			continue // temporary files of the installer are not kept
+		}
+		seen[rel] = true
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	sort.Strings(paths)
+	b, err := json.Marshal(paths)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(getInstallerOutputsPath(id), b, 0644)
+}
+
+// readInstallerOutputs returns the slash separated paths, relative to the launcher root, of the files the installer
+// produced for the profile
+func readInstallerOutputs(id string) ([]string, error) {
+	b, err := os.ReadFile(getInstallerOutputsPath(id))
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	err = json.Unmarshal(b, &paths)
+	if err != nil {
+		return nil, errors.WithMessage(err, "corrupt installer outputs of "+id)
+	}
+	return paths, nil
+}
+
+// readMainClass returns the Main-Class of the manifest of the jar
+func readMainClass(jar string) (string, error) {
+	r, err := zip.OpenReader(jar)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	b, err := readZipFile(&r.Reader, "META-INF/MANIFEST.MF")
+	if err != nil {
+		return "", err
+	}
+	scanner := bufio.NewScanner(strings.NewReader(string(b)))
+	for scanner.Scan() {
+		if value := strings.TrimPrefix(scanner.Text(), "Main-Class:"); value != scanner.Text() {
+			return strings.TrimSpace(value), nil
+		}
+	}
+	return "", errors.New(jar + " declares no main class")
+}
+
+func readZipFile(r *zip.Reader, name string) ([]byte, error) {
+	f, err := r.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+func readZipJSON(r *zip.Reader, name string, v any) error {
+	b, err := readZipFile(r, name)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// receiveMavenVersions returns the versions of the maven metadata accepted by match, newest first
+func receiveMavenVersions(ctx context.Context, address string, match func(string) bool) ([]string, error) {
+	b, err := fetchMetadata(ctx, address, "")
+	if err != nil {
+		return nil, err
+	}
+	var metadata mavenMetadata
+	err = xml.Unmarshal(b, &metadata)
+	if err != nil {
+		removeCache(address)
+		return nil, err
+	}
+	var versions []string
+	for _, v := range metadata.Versions {
+		if match(v) {
+			versions = append(versions, v)
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return compareBuilds(versions[i], versions[j]) > 0
+	})
+	return versions, nil
+}
+
+// compareBuilds compares the numbers of two build names in order, on a tie the name without suffix,
+// which marks a pre-release, is newer
+func compareBuilds(a string, b string) int {
+	split := func(s string) []int {
+		var nums []int
+		for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r < '0' || r > '9' }) {
+			n, _ := strconv.Atoi(f)
+			nums = append(nums, n)
+		}
+		return nums
+	}
+	x, y := split(a), split(b)
+	for i := 0; i < len(x) && i < len(y); i++ {
+		if x[i] != y[i] {
+			return x[i] - y[i]
+		}
+	}
+	if len(x) != len(y) {
+		return len(x) - len(y)
+	}
+	return strings.Compare(b, a)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
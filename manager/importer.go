@@ -0,0 +1,181 @@
+package manager
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"io/fs"
+	"launcher/manager/comp"
+	"os"
+	"path/filepath"
+)
+
+// ImportReport summarizes an import from an existing game directory
+type ImportReport struct {
+	Imported int   `json:"imported"` // files copied or linked
+	Linked   int   `json:"linked"`   // files among Imported that were hardlinked
+	Bytes    int64 `json:"bytes"`    // size of the imported files
+	Present  int   `json:"present"`  // files already valid in the launcher root
+	Rejected int   `json:"rejected"` // files failing verification, they are downloaded by the next install
+}
+
+// ImportMinecraft imports the assets, libraries and versions of the game directory src, usually the vanilla
+// launcher's .minecraft, into the launcher root. Every file is verified against its hash before being imported.
+// With link set, files are hardlinked instead of copied where the file system allows it.
+// Versions and libraries are verified through the version manifest, when it is unavailable only assets are imported.
+func ImportMinecraft(ctx context.Context, src string, link bool) (ImportReport, error) {
+	if _, err := os.Stat(filepath.Join(src, "versions")); err != nil {
+		return ImportReport{}, errors.Errorf("%s is not a minecraft directory", src)
+	}
+	im := importer{ctx: ctx, link: link}
+
+	err := im.importObjects(filepath.Join(src, "assets", "objects"))
+	if err != nil {
+		return im.report, errors.WithMessage(err, "failed to import assets")
+	}
+
+	mf, err := GetManifest()
+	if err != nil {
+		return im.report, errors.WithMessage(err, "failed to fetch manifest, only assets were imported")
+	}
+	dir, err := os.ReadDir(filepath.Join(src, "versions"))
+	if err != nil {
+		return im.report, err
+	}
+	versions := make(map[string]ManifestVersion)
+	for _, v := range mf.Versions {
+		versions[v.ID] = v
+	}
+	for _, entry := range dir {
+		v, ok := versions[entry.Name()]
+		if !entry.IsDir() || !ok {
+			continue // loader profiles are not listed in the manifest and cannot be verified
+		}
+		err = im.importVersion(src, v)
+		if err != nil {
+			return im.report, errors.WithMessage(err, "failed to import version "+v.ID)
+		}
+	}
+	return im.report, nil
+}
+
+/* PRIVATE REGION */
+
+type importer struct {
+	ctx    context.Context
+	link   bool
+	report ImportReport
+}
+
+// importObjects imports the asset objects below dir, whose names are their hashes
+func (im *importer) importObjects(dir string) error {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		hash := d.Name()
+		if len(hash) != 40 || filepath.Base(filepath.Dir(path)) != hash[0:2] {
+			return nil // not an object
+		}
+		asset := Asset{Hash: hash}
+		return im.importFile(path, asset.GetObjectPath(), hash)
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// importVersion imports the version JSON, client jar, asset index, log configuration and libraries of
+// a vanilla version, provided its JSON matches the manifest
+func (im *importer) importVersion(src string, v ManifestVersion) error {
+	dir := filepath.Join(src, "versions", v.ID)
+	if !checkSHA1Hash(filepath.Join(dir, v.ID+".json"), v.SHA1) {
+		im.report.Rejected++
+		return nil // modified or corrupt, its hashes cannot be trusted
+	}
+	ver, err := readVersionFile(filepath.Join(dir, v.ID+".json"))
+	if err != nil {
+		return err
+	}
+
+	files := [][3]string{
+		{filepath.Join(dir, v.ID+".json"), GetVersionFilePath(v.ID), v.SHA1},
+		{filepath.Join(dir, v.ID+".jar"), GetVersionJARPath(v.ID), ver.Downloads["client"].SHA1},
+	}
+	if ver.AssetIndex.ID != "" {
+		files = append(files, [3]string{filepath.Join(src, "assets", "indexes", ver.AssetIndex.ID+".json"), getAssetIndexPath(ver), ver.AssetIndex.SHA1})
+	}
+	if file := ver.Logging.Client.File; file.ID != "" {
+		files = append(files, [3]string{filepath.Join(src, "assets", "log_configs", file.ID), filepath.Join(comp.GetLogCfgsPath(), file.ID), file.SHA1})
+	}
+	env := CurrentEnvironment()
+	for _, lib := range ver.Libraries {
+		if !EvaluateRules(lib.Rules, env) {
+			continue
+		}
+		for _, artifact := range lib.GetArtifacts(env) {
+			path := filepath.FromSlash(artifact.Path)
+			files = append(files, [3]string{filepath.Join(src, "libraries", path), filepath.Join(comp.GetLibraryPath(), path), artifact.SHA1})
+		}
+	}
+
+	for _, file := range files {
+		if _, err := os.Stat(file[0]); err != nil {
+			continue // never downloaded by the vanilla launcher
+		}
+		err = im.importFile(file[0], file[1], file[2])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importFile copies or links src to dst when src matches hash and dst does not
+func (im *importer) importFile(src string, dst string, hash string) error {
+	if im.ctx.Err() != nil {
+		return im.ctx.Err()
+	}
+	if hash == "" {
+		im.report.Rejected++
+		return nil
+	}
+	if checkSHA1Hash(dst, hash) {
+		im.report.Present++
+		return nil
+	}
+	if !checkSHA1Hash(src, hash) {
+		im.report.Rejected++
+		return nil
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(dst), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	_ = os.Remove(dst)
+	if im.link && os.Link(src, dst) == nil {
+		im.report.Linked++
+	} else {
+		// copied to a part file first, so that an interrupted copy is never taken for a valid file
+		err = copyFile(src, dst+partSuffix)
+		if err != nil {
+			_ = os.Remove(dst + partSuffix)
+			return err
+		}
+		err = os.Rename(dst+partSuffix, dst)
+		if err != nil {
+			return err
+		}
+	}
+	im.report.Imported++
+	im.report.Bytes += info.Size()
+	return nil
+}
@@ -1,182 +1,234 @@
 package manager
 
 import (
+	"context"
 	"crypto/sha1"
-	"encoding/json"
 	"fmt"
 	"github.com/pkg/errors"
 	"io"
 	"launcher/events"
 	"launcher/logging"
 	"launcher/manager/comp"
-	"net/http"
+	"launcher/manager/maven"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strings"
 )
 
-const fabricUrl = "https://maven.fabricmc.net/net/fabricmc/fabric-installer/0.11.0/fabric-installer-0.11.0.jar"
-
-type installHandle struct {
-	pbar ProgressBar
-}
-
-func InstallTheOnlyProfile(dir string) error {
-	events.ProgressUpdateEvent.Trigger(events.ProgressUpdateEventPayload{Progress: 1, Message: "Downloading fabric"})
-	installer, err := downloadFabric()
-	if err != nil {
-		return errors.WithMessage(err, "failed to download fabric installer")
+// InstallProfile installs a profile of the loader kind, fabric when empty, for the given minecraft version into the launcher root.
+// It stops once ctx is cancelled, removing the files it was writing, and returns the error of ctx.
+// Completed steps are recorded in the install journal, so that a failed install resumes where it stopped.
+func InstallProfile(ctx context.Context, version string, kind string) error {
+	events.ProgressUpdateEvent.Trigger(events.ProgressUpdateEventPayload{Progress: 0, Message: "Fetching manifest", Stage: StageMetadata, ETA: -1})
+	if kind == "" {
+		kind = LoaderFabric
+	}
+	journal := resumeJournal(version, kind)
+	run := func(step string, install func() error) error {
+		if journal.IsDone(step) {
+			return nil
+		}
+		err := install()
+		if err != nil {
+			return err
+		}
+		return journal.markDone(step)
 	}
-	logging.Logger.Print("Downloaded fabric to " + installer)
-	events.ProgressUpdateEvent.Trigger(events.ProgressUpdateEventPayload{Progress: 5, Message: "Installing fabric"})
 
-	err = installFabric(installer, dir, GlobalMinecraftVersion)
-	logging.Logger.Print("Fabric installed to " + dir)
-	//TODO download and install fabric manually
+	err := run(StepVersion, func() error {
+		mf, err := GetManifest()
+		if err != nil {
+			return errors.WithMessage(err, "failed to fetch manifest")
+		}
+		err = writeVanillaVersion(ctx, mf, version)
+		if err != nil {
+			return errors.WithMessage(err, "failed to write version data")
+		}
+		return nil
+	})
 	if err != nil {
-		return errors.WithMessage(err, "failed to install fabric")
-	}
-	events.ProgressUpdateEvent.Trigger(events.ProgressUpdateEventPayload{Progress: 10, Message: "Fetching manifest"})
-	mf, _ := GetManifest()
-	ver, _ := mf.GetVersion(GlobalMinecraftVersion)
-	//TODO error check
-	events.ProgressUpdateEvent.Trigger(events.ProgressUpdateEventPayload{Progress: 10, Message: "Downloading logging library"})
-	err = downloadLoggingLib(ver)
+		return err
+	}
+	vanilla, err := LoadVersion(version)
 	if err != nil {
-		return errors.WithMessage(err, "failed to download logging library")
+		return errors.WithMessage(err, "failed to load version data")
 	}
 
-	_ = os.MkdirAll(comp.GetIndexesPath(), os.ModePerm)
-	h, err := os.Create(filepath.Join(comp.GetIndexesPath(), ver.AssetIndex.ID+".json"))
-	var data map[string]interface{}
+	installProgress.begin(plannedSize(vanilla, CurrentEnvironment(), journal))
+	defer installProgress.end()
 
-	err = receiveJSONObject(ver.AssetIndex.Url, &data)
+	err = run(StepJava, func() error {
+		installProgress.setStage(StageJava, "Installing Java runtime")
+		if _, err := javaRuntimePlatform(CurrentEnvironment()); err != nil {
+			logging.Logger.Warning(err.Error()) // the game files are still installed, the launch needs a pinned java
+			return nil
+		}
+		_, err := InstallJavaRuntime(ctx, vanilla.GetJavaVersion())
+		if err != nil {
+			return errors.WithMessage(err, "failed to install java runtime")
+		}
+		return nil
+	})
 	if err != nil {
-		return errors.WithMessage(err, "failed to asset index")
+		return err
 	}
 
-	b, _ := json.Marshal(data)
-	_, err = h.Write(b)
+	err = run(StepLoader, func() error {
+		installProgress.setStage(StageLoader, "Installing "+kind)
+		profile, err := installLoader(ctx, kind, vanilla, "")
+		if err != nil {
+			return errors.WithMessage(err, "failed to install "+kind)
+		}
+		logging.Logger.Print("Loader profile " + profile + " installed")
+		journal.Profile = profile
+		return nil
+	})
 	if err != nil {
-		return errors.WithMessage(err, "failed to write asset index")
+		return err
 	}
-	_ = h.Close()
-
-	_, err = downloadAssets(ver)
+	ver, err := LoadVersion(journal.Profile)
 	if err != nil {
-		return errors.WithMessage(err, "failed to download assets")
+		return errors.WithMessage(err, "failed to load version data")
 	}
-	_, err = downloadLibraries(ver)
-	if err != nil {
-		return errors.WithMessage(err, "failed to download libraries")
+	if !journal.IsDone(StepLibraries) {
+		// the libraries of the loader were not known when the install began
+		installProgress.expect(plannedSize(ver, CurrentEnvironment(), journal) - plannedSize(vanilla, CurrentEnvironment(), journal))
 	}
-	return nil
-}
-
-// PRIVATE REGION //
 
-func downloadLoggingLib(version Version) error {
-	r, err := http.Get(version.Logging.Client.File.Url)
+	err = run(StepClient, func() error {
+		installProgress.setStage(StageClient, "Downloading Minecraft")
+		err := downloadLoggingLib(ctx, ver)
+		if err != nil {
+			return errors.WithMessage(err, "failed to download logging library")
+		}
+		jar := GetVersionJARPath(ver.Jar)
+		if checkFile(jar, ver.Downloads["client"].SHA1) {
+			installProgress.add(ver.Downloads["client"].Size)
+			return nil
+		}
+		err = installMinecraft(ctx, jar, ver)
+		if err != nil {
+			return errors.WithMessage(err, "failed to download minecraft client")
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-	b, err := io.ReadAll(r.Body)
-
-	_ = os.MkdirAll(comp.GetLogCfgsPath(), os.ModePerm)
 
-	h, err := os.Create(filepath.Join(comp.GetLogCfgsPath(), version.Logging.Client.File.ID))
+	err = run(StepAssetIndex, func() error {
+		return writeAssetIndex(ctx, ver)
+	})
 	if err != nil {
 		return err
 	}
-	defer h.Close()
-	_, err = h.Write(b)
+
+	err = run(StepAssets, func() error {
+		installProgress.setStage(StageAssets, "Downloading assets")
+		_, err := downloadAssets(ctx, ver)
+		if err != nil {
+			return errors.WithMessage(err, "failed to download assets")
+		}
+		assets, err := ver.GetAssets()
+		if err != nil {
+			return errors.WithMessage(err, "failed to read asset index")
+		}
+		err = assets.Materialize(ver.AssetIndex.ID, comp.GetLauncherRoot())
+		if err != nil {
+			return errors.WithMessage(err, "failed to copy assets into their legacy layout")
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-	return nil
-}
 
-func installMinecraft(file string, version Version) error {
-	r, err := http.Get(version.Downloads["client"].Url)
+	err = run(StepLibraries, func() error {
+		installProgress.setStage(StageLibraries, "Downloading libraries")
+		_, err := downloadLibraries(ctx, ver)
+		if err != nil {
+			return errors.WithMessage(err, "failed to download libraries")
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-	b, err := io.ReadAll(r.Body)
-
-	_ = os.MkdirAll(filepath.Dir(file), os.ModePerm)
 
-	h, err := os.OpenFile(file, os.O_WRONLY, os.ModePerm)
+	err = journal.complete()
 	if err != nil {
-		return err
+		return errors.WithMessage(err, "failed to write install journal")
 	}
-	defer h.Close()
-	_, err = h.Write(b)
+	_, err = UpdateStoreIndex()
 	if err != nil {
-		return err
+		logging.Logger.Warning("Failed to update the store index, caused by: " + err.Error())
 	}
-
+	installProgress.complete()
 	return nil
 }
 
-func downloadFabric() (string, error) {
-	r, err := http.Get(fabricUrl)
-	if err != nil {
-		return "", err
-	}
-	b, err := io.ReadAll(r.Body)
+// PRIVATE REGION //
 
-	h, err := os.CreateTemp("", "fabric-installer")
-	if err != nil {
-		return "", err
+// plannedSize returns the bytes of the client, logging configuration, assets and libraries of the version
+// that the pending steps of the journal download or verify
+func plannedSize(ver Version, env Environment, journal InstallJournal) int64 {
+	var size int64
+	if !journal.IsDone(StepClient) {
+		size += ver.Downloads["client"].Size + ver.Logging.Client.File.Size
 	}
-	defer h.Close()
-	_, err = h.Write(b)
-	if err != nil {
-		return "", err
+	if !journal.IsDone(StepAssets) {
+		size += int64(ver.AssetIndex.TotalSize)
 	}
-	return h.Name(), nil
-}
-
-func checkJava() error {
-	required := "17.0.0"
-	cmd := exec.Command("java", "--version")
-	o, err := cmd.CombinedOutput()
-	if err != nil {
-		return errors.New("invalid java on machine, required version: >=" + required + " current version: " + "n/a")
+	if journal.IsDone(StepLibraries) {
+		return size
 	}
-	str := string(o)
-	words := strings.Split(str, " ")
-	version := words[1]
-	fmt.Println(version)
-	if !CompareVersion("17.0.0", version, PrecisionFull) {
-		return errors.New("invalid java on machine, required version: >=" + required + " current version: " + version)
+	for _, lib := range ver.Libraries {
+		if !EvaluateRules(lib.Rules, env) {
+			continue
+		}
+		for _, artifact := range lib.GetArtifacts(env) {
+			size += artifact.Size
+		}
 	}
-	return nil
+	return size
 }
 
-func installFabric(installer string, dir string, version string) error {
-	if _, err := os.Stat(dir); err != nil {
-		err := os.MkdirAll(dir, os.ModePerm)
-		if err != nil {
-			return err
+// writeVanillaVersion stores the vanilla version JSON in its profile directory, so that loader profiles can inherit from it
+func writeVanillaVersion(ctx context.Context, mf Manifest, version string) error {
+	for _, v := range mf.Versions {
+		if v.ID == version {
+			b, err := fetchMetadata(ctx, v.Url, v.SHA1)
+			if err != nil {
+				return err
+			}
+			err = os.MkdirAll(filepath.Dir(GetVersionFilePath(version)), os.ModePerm)
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(GetVersionFilePath(version), b, 0644)
 		}
 	}
+	return errors.Errorf("version \"%s\" not found in the manifest file", version)
+}
 
-	//TODO included java bin
-	cmd := exec.Command("java", "-jar", installer, "client", "-dir", dir, "-mcversion", version)
-
-	//b, _ := cmd.CombinedOutput()
-	//fmt.Println(string(b))
-	if err := checkJava(); err == nil {
-		_ = cmd.Run()
+func downloadLoggingLib(ctx context.Context, version Version) error {
+	file := version.Logging.Client.File
+	if file.ID == "" {
+		return nil // old versions do not configure logging
+	}
+	path := filepath.Join(comp.GetLogCfgsPath(), file.ID)
+	if checkSHA1Hash(path, file.SHA1) {
+		installProgress.add(file.Size)
 		return nil
-	} else {
-		return err
 	}
+	return downloadFile(ctx, file.Url, path, file.SHA1, file.Size)
+}
+
+func installMinecraft(ctx context.Context, file string, version Version) error {
+	client := version.Downloads["client"]
+	return downloadFile(ctx, client.Url, file, client.SHA1, client.Size)
 }
 
-func downloadAssets(version Version) ([]string, error) {
+func downloadAssets(ctx context.Context, version Version) ([]string, error) {
 	if _, err := os.Stat(comp.GetAssetsPath()); err != nil {
 		err := os.MkdirAll(comp.GetAssetsPath(), os.ModePerm)
 		if err != nil {
@@ -189,57 +241,66 @@ func downloadAssets(version Version) ([]string, error) {
 	}
 	var paths []string
 
-	piece := float64(40) / float64(len(asts))
-	var progress = 10.0
+	sched := newScheduler()
+	sched.onProgress = func(done int, total int) {
+		installProgress.setMessage(fmt.Sprintf("Downloading asset %d/%d", done, total))
+	}
 
-	var counter = 1
+	scheduled := make(map[string]bool)
+	for name, asset := range asts.Objects {
+		paths = append(paths, filepath.Join(comp.GetAssetsPath(), name))
+		if scheduled[asset.Hash] {
+			installProgress.add(asset.Size) // several names may share one object
+			continue
+		}
+		scheduled[asset.Hash] = true
 
-	for name, asset := range asts {
-		var download = func() {
-			res, err := downloadAsset(asset)
-			//fmt.Printf("[%d/%d] ASSET: %s %s\n", counter, len(asts), codeToString(res), name) //TODO: log
+		name, asset := name, asset
+		sched.add(name, resourceUrl(asset.Hash), func() (resourceStatus, error) {
+			res, err := downloadAsset(ctx, asset)
 			if res == Failed {
 				logging.Logger.Error("Failed to download asset " + name + "\n\tcaused by: " + err.Error())
 			}
-			paths = append(paths, filepath.Join(comp.GetAssetsPath(), name))
-
-			progress += piece
-			events.ProgressUpdateEvent.Trigger(events.ProgressUpdateEventPayload{Progress: progress, Message: fmt.Sprintf("Downloading asset %d/%d", counter, len(asts))})
-
-			counter++
-		}
+			return res, err
+		})
+	}
 
-		download()
+	err = sched.run(ctx)
+	if err != nil {
+		return []string{}, err
 	}
 	return paths, nil
 }
 
-func downloadLibraries(ver Version) ([]string, error) {
+func downloadLibraries(ctx context.Context, ver Version) ([]string, error) {
 	var paths []string
+	env := CurrentEnvironment()
 
-	piece := float64(40) / float64(len(ver.Libraries))
-	progress := 55.0
+	sched := newScheduler()
+	sched.onProgress = func(done int, total int) {
+		installProgress.setMessage(fmt.Sprintf("Downloading library %d/%d", done, total))
+	}
 
-	for i, library := range ver.Libraries {
-		res, err := downloadLibrary(library)
-		if err != nil {
-			//TODO: log
-			return []string{}, err
-		}
-		//fmt.Printf("[%d/%d] LIBRARY: %s %s \n", i+1, len(ver.Libraries), codeToString(res), library.Name) //TODO: log
-		if res == Failed {
-			logging.Logger.Error("Failed to download library " + library.Name + "\n\tcaused by: " + err.Error())
-		}
-		progress += piece
+	for _, library := range ver.Libraries {
+		paths = append(paths, filepath.Join(comp.GetLibraryPath(), library.GetArtifact().Path))
 
-		events.ProgressUpdateEvent.Trigger(events.ProgressUpdateEventPayload{Progress: progress, Message: fmt.Sprintf("Downloading library %d/%d", i+1, len(ver.Libraries))})
-		paths = append(paths, filepath.Join(comp.GetLibraryPath(), library.Downloads.Artifact.Path))
+		library := library
+		sched.add(library.Name, library.GetArtifact().Url, func() (resourceStatus, error) {
+			res, err := downloadLibrary(ctx, library, env)
+			if res == Failed {
+				logging.Logger.Error("Failed to download library " + library.Name + "\n\tcaused by: " + err.Error())
+			}
+			return res, err
+		})
+	}
+
+	err := sched.run(ctx)
+	if err != nil {
+		return []string{}, err
 	}
 	return paths, nil
 }
 
-const resourceUrl = "https://resources.download.minecraft.net/%s/%s"
-
 type resourceStatus int8
 
 const (
@@ -263,95 +324,91 @@ func codeToString(code resourceStatus) string {
 	}
 }
 
-func downloadAsset(a Asset) (resourceStatus, error) {
-	dir := comp.GetAssetsPath()
-	if _, err := os.Stat(filepath.Join(dir, "objects", a.Hash[0:2], a.Hash)); err != os.ErrNotExist {
-		if checkSHA1Hash(filepath.Join(dir, "objects", a.Hash[0:2], a.Hash), a.Hash) {
-			return Skipped, nil // Already exists, skip
-		}
-	}
-
-	r, err := http.Get(fmt.Sprintf(resourceUrl, a.Hash[0:2], a.Hash))
-	if err != nil {
-		return Failed, err
+func downloadAsset(ctx context.Context, a Asset) (resourceStatus, error) {
+	if checkSHA1Hash(a.GetObjectPath(), a.Hash) {
+		installProgress.add(a.Size)
+		return Skipped, nil // Already exists, skip
 	}
-	//TODO: gzip
 
-	b, err := io.ReadAll(r.Body)
+	err := downloadFile(ctx, resourceUrl(a.Hash), a.GetObjectPath(), a.Hash, a.Size)
 	if err != nil {
 		return Failed, err
 	}
+	return Downloaded, nil
+}
 
-	err = os.MkdirAll(filepath.Join(dir, "objects", a.Hash[0:2]), os.ModePerm)
-	if err != nil {
-		return Failed, err
+func downloadLibrary(ctx context.Context, lib Library, env Environment) (resourceStatus, error) {
+	if !EvaluateRules(lib.Rules, env) {
+		return NotRequired, nil // Not required on this system, skip
 	}
 
-	h, err := os.Create(filepath.Join(dir, "objects", a.Hash[0:2], a.Hash))
-	if err != nil {
-		return Failed, err
-	}
-	defer h.Close()
-	_, err = h.Write(b)
-	if err != nil {
-		return Failed, err
+	status := Skipped
+	for _, artifact := range lib.GetArtifacts(env) {
+		res, err := downloadLibraryArtifact(ctx, lib, artifact)
+		if err != nil {
+			return res, err
+		}
+		if res == Downloaded {
+			status = Downloaded
+		}
 	}
-
-	return Downloaded, nil
+	return status, nil
 }
 
-func downloadLibrary(lib Library) (resourceStatus, error) {
+func downloadLibraryArtifact(ctx context.Context, lib Library, artifact Artifact) (resourceStatus, error) {
 	dir := comp.GetLibraryPath()
-	var skip = false
-	for _, rule := range lib.Rules {
-		if !rule.Complies() {
-			skip = true
+	if artifact.Url == "" {
+		// libraries without url are extracted or generated by a loader installer
+		if checkFile(filepath.Join(dir, artifact.Path), artifact.SHA1) {
+			return Skipped, nil
 		}
+		return Failed, errors.New("no download url known for library: " + lib.Name)
 	}
-
-	if skip {
-		return NotRequired, nil // Not required on this system, skip
+	if artifact.SHA1 == "" {
+		// loader metadata omits hashes, the resolver verifies the checksums the repositories publish
+		return resolveLibraryArtifact(ctx, lib, artifact)
 	}
 
-	if _, err := os.Stat(filepath.Join(dir, lib.Downloads.Artifact.Path)); err != os.ErrNotExist {
-		if checkSHA1Hash(filepath.Join(dir, lib.Downloads.Artifact.Path), lib.Downloads.Artifact.SHA1) {
-			return Skipped, nil // Already exists, skip
-		}
+	if checkFile(filepath.Join(dir, artifact.Path), artifact.SHA1) {
+		installProgress.add(artifact.Size)
+		return Skipped, nil // Already exists, skip
 	}
 
-	r, err := http.Get(lib.Downloads.Artifact.Url)
+	err := downloadFile(ctx, artifact.Url, filepath.Join(dir, artifact.Path), artifact.SHA1, artifact.Size)
 	if err != nil {
-		return Failed, err
+		return Failed, errors.WithMessage(err, "failed to download library "+lib.Name)
 	}
+	return Downloaded, nil
+}
 
-	//TODO: gzip
-
-	b, err := io.ReadAll(r.Body)
+// resolveLibraryArtifact downloads the main artifact of the library from its repository or a configured one
+func resolveLibraryArtifact(ctx context.Context, lib Library, artifact Artifact) (resourceStatus, error) {
+	c, err := maven.Parse(lib.Name)
 	if err != nil {
 		return Failed, err
 	}
-
-	err = os.MkdirAll(filepath.Dir(filepath.Join(dir, lib.Downloads.Artifact.Path)), os.ModePerm)
-	if err != nil {
-		return Failed, err
+	repository := lib.Url
+	if repository == "" {
+		repository = currentEndpoints().Libraries
 	}
-
-	h, err := os.Create(filepath.Join(dir, lib.Downloads.Artifact.Path))
+	downloaded, err := libraryResolver().Resolve(ctx, c, repository, filepath.Join(comp.GetLibraryPath(), artifact.Path))
 	if err != nil {
 		return Failed, err
 	}
-	defer h.Close()
-
-	_, err = h.Write(b)
-
-	if !checkSHA1Hash(filepath.Join(dir, lib.Downloads.Artifact.Path), lib.Downloads.Artifact.SHA1) {
-		return Failed, errors.New("failed to verify checksum of downloaded library: " + lib.Name)
+	if !downloaded {
+		installProgress.add(artifact.Size)
+		return Skipped, nil
 	}
+	return Downloaded, nil
+}
 
-	if err != nil {
-		return Failed, err
+// checkFile verifies the file against the hash, or only checks its presence when no hash is known
+func checkFile(path string, hash string) bool {
+	if hash == "" {
+		_, err := os.Stat(path)
+		return err == nil
 	}
-	return Downloaded, nil
+	return checkSHA1Hash(path, hash)
 }
 
 func checkSHA1Hash(path string, hash string) bool {
@@ -0,0 +1,188 @@
+package manager
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/pkg/errors"
+	"launcher/manager/comp"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// JavaInstallation is a java runtime found on the machine
+type JavaInstallation struct {
+	Home       string `json:"home"`
+	Executable string `json:"executable"`
+	Vendor     string `json:"vendor"`
+	Version    string `json:"version"`
+	Major      int    `json:"major"`
+	Minor      int    `json:"minor"`
+	Arch       string `json:"arch"`    // x86, x86_64, arm32 or arm64
+	Managed    bool   `json:"managed"` // installed by the launcher
+}
+
+// DiscoverJava scans JAVA_HOME, PATH, the usual system locations and the launcher's own runtimes for java installations
+func DiscoverJava() []JavaInstallation {
+	var homes []string
+	if home := os.Getenv("JAVA_HOME"); home != "" {
+		homes = append(homes, home)
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		exe, err := filepath.EvalSymlinks(filepath.Join(dir, "java"+executableSuffix()))
+		if err == nil {
+			homes = append(homes, filepath.Dir(filepath.Dir(exe)))
+		}
+	}
+	for _, dir := range comp.GetJavaSearchPaths() {
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			homes = append(homes, filepath.Join(dir, entry.Name()))
+		}
+	}
+	components, _ := os.ReadDir(comp.GetRuntimesPath())
+	for _, component := range components {
+		if component.IsDir() {
+			homes = append(homes, GetJavaRuntimePath(component.Name()))
+		}
+	}
+
+	seen := make(map[string]bool)
+	var ret []JavaInstallation
+	for _, home := range homes {
+		if resolved, err := filepath.EvalSymlinks(home); err == nil {
+			home = resolved
+		}
+		home = filepath.Clean(home)
+		if seen[home] {
+			continue
+		}
+		seen[home] = true
+		java, err := InspectJava(home)
+		if err == nil {
+			ret = append(ret, java)
+		}
+	}
+	return ret
+}
+
+// InspectJava describes the java installation in home, reading its release file
+// or falling back to the properties reported by the runtime itself
+func InspectJava(home string) (JavaInstallation, error) {
+	java := JavaInstallation{
+		Home:       home,
+		Executable: filepath.Join(home, "bin", "java"+executableSuffix()),
+		Managed:    strings.HasPrefix(home, comp.GetRuntimesPath()),
+	}
+	if _, err := os.Stat(java.Executable); err != nil {
+		return JavaInstallation{}, errors.New("no java executable in " + home)
+	}
+
+	props, err := readJavaRelease(filepath.Join(home, "release"))
+	if err == nil && props["JAVA_VERSION"] != "" {
+		java.Version = props["JAVA_VERSION"]
+		java.Vendor = props["IMPLEMENTOR"]
+		java.Arch = javaArch(props["OS_ARCH"])
+	} else {
+		props, err = readJavaProperties(java.Executable)
+		if err != nil {
+			return JavaInstallation{}, errors.WithMessage(err, "failed to inspect java in "+home)
+		}
+		java.Version = props["java.version"]
+		java.Vendor = props["java.vendor"]
+		java.Arch = javaArch(props["os.arch"])
+	}
+
+	var ok bool
+	java.Major, java.Minor, ok = ParseJavaVersion(java.Version)
+	if !ok {
+		return JavaInstallation{}, errors.New("unrecognized java version " + java.Version)
+	}
+	return java, nil
+}
+
+// ParseJavaVersion returns the major and minor version of a java version string,
+// understanding both the legacy 1.8.0_312 and the current 17.0.2.1 schemes
+func ParseJavaVersion(version string) (int, int, bool) {
+	match := javaVersionRegex.FindStringSubmatch(version)
+	if match == nil {
+		return 0, 0, false
+	}
+	major, _ := strconv.Atoi(match[1])
+	minor := 0
+	if match[2] != "" {
+		minor, _ = strconv.Atoi(match[2])
+	}
+	if major == 1 && match[2] != "" {
+		major, minor = minor, 0 // 1.8.0_312 is java 8
+		if match[3] != "" {
+			minor, _ = strconv.Atoi(match[3])
+		}
+	}
+	return major, minor, true
+}
+
+/* PRIVATE REGION */
+
+var javaVersionRegex = regexp.MustCompile(`^(\d+)(?:\.(\d+))?(?:\.(\d+))?`)
+
+// readJavaRelease parses the KEY="value" pairs of the release file of a jdk
+func readJavaRelease(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	props := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), "=")
+		if found {
+			props[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), "\"")
+		}
+	}
+	return props, nil
+}
+
+// readJavaProperties parses the system properties printed by java -XshowSettings:properties
+func readJavaProperties(executable string) (map[string]string, error) {
+	o, err := exec.Command(executable, "-XshowSettings:properties", "-version").CombinedOutput()
+	if err != nil {
+		return nil, err
+	}
+	props := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(o))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), " = ")
+		if found {
+			props[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return props, nil
+}
+
+// javaArch converts the os.arch property of java to Mojang's naming
+func javaArch(arch string) string {
+	switch arch {
+	case "amd64", "x86_64":
+		return "x86_64"
+	case "x86", "i386", "i586", "i686":
+		return "x86"
+	case "aarch64", "arm64":
+		return "arm64"
+	case "arm", "aarch32":
+		return "arm32"
+	default:
+		return arch
+	}
+}
+
+func executableSuffix() string {
+	if runtime.GOOS == "windows" {
+		return ".exe"
+	}
+	return ""
+}
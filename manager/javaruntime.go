@@ -0,0 +1,233 @@
+package manager
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/ulikunitz/xz/lzma"
+	"io"
+	"launcher/manager/comp"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+)
+
+// JavaVersion is the java runtime a version requires
+type JavaVersion struct {
+	Component    string `json:"component"`
+	MajorVersion int    `json:"majorVersion"`
+}
+
+// legacyJavaVersion is used by versions which predate the javaVersion field
+var legacyJavaVersion = JavaVersion{Component: "jre-legacy", MajorVersion: 8}
+
+// javaRuntimes maps platforms to the runtime components available for them
+type javaRuntimes map[string]map[string][]struct {
+	Manifest Artifact `json:"manifest"`
+	Version  struct {
+		Name     string `json:"name"`
+		Released string `json:"released"`
+	} `json:"version"`
+}
+
+type javaRuntimeManifest struct {
+	Files map[string]javaRuntimeFile `json:"files"`
+}
+
+type javaRuntimeFile struct {
+	Type       string `json:"type"` // file, directory or link
+	Executable bool   `json:"executable"`
+	Target     string `json:"target"`
+	Downloads  struct {
+		Raw  Artifact `json:"raw"`
+		LZMA Artifact `json:"lzma"`
+	} `json:"downloads"`
+}
+
+// GetJavaVersion returns the java runtime the version requires
+func (v *Version) GetJavaVersion() JavaVersion {
+	if v.JavaVersion.Component == "" {
+		return legacyJavaVersion
+	}
+	return v.JavaVersion
+}
+
+// GetJavaRuntimePath returns the directory the runtime component is installed in
+func GetJavaRuntimePath(component string) string {
+	env := CurrentEnvironment()
+	platform, err := javaRuntimePlatform(env)
+	if err != nil {
+		platform = env.OS + "-" + env.Arch // never installed, a runtime is pinned instead
+	}
+	return filepath.Join(comp.GetRuntimesPath(), component, platform)
+}
+
+// InstallJavaRuntime installs the runtime component from Mojang's java-runtime manifest, verifying every file,
+// and returns the path of its java executable. An installation matching the manifest is left untouched.
+func InstallJavaRuntime(ctx context.Context, java JavaVersion) (string, error) {
+	platform, err := javaRuntimePlatform(CurrentEnvironment())
+	if err != nil {
+		return "", err
+	}
+	var runtimes javaRuntimes
+	err = receiveJSONObject(ctx, javaRuntimesUrl(), &runtimes)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to fetch java runtime list")
+	}
+	available := runtimes[platform][java.Component]
+	if len(available) == 0 {
+		return "", errors.Errorf("java runtime %s is not available for %s", java.Component, platform)
+	}
+	manifest := available[0].Manifest
+
+	dir := GetJavaRuntimePath(java.Component)
+	marker := dir + ".sha1"
+	if b, err := os.ReadFile(marker); err == nil && string(b) == manifest.SHA1 {
+		return comp.GetJavaExecutable(dir), nil
+	}
+
+	var files javaRuntimeManifest
+	err = receiveVerifiedJSONObject(ctx, manifest.Url, manifest.SHA1, &files)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to fetch java runtime manifest")
+	}
+
+	var size int64
+	for _, file := range files.Files {
+		size += file.Downloads.Raw.Size
+	}
+	installProgress.expect(size)
+
+	// Directories sort before their content, links are created once their targets exist
+	var paths []string
+	for path := range files.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var links []string
+	for _, path := range paths {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		file := files.Files[path]
+		target := filepath.Join(dir, filepath.FromSlash(path))
+		switch file.Type {
+		case "directory":
+			err = os.MkdirAll(target, os.ModePerm)
+		case "file":
+			err = installRuntimeFile(ctx, file, target)
+		case "link":
+			links = append(links, path)
+		}
+		if err != nil {
+			return "", errors.WithMessage(err, "failed to install "+path)
+		}
+	}
+	if runtime.GOOS != "windows" {
+		for _, path := range links {
+			target := filepath.Join(dir, filepath.FromSlash(path))
+			_ = os.Remove(target)
+			err = os.Symlink(files.Files[path].Target, target)
+			if err != nil {
+				return "", errors.WithMessage(err, "failed to link "+path)
+			}
+		}
+	}
+
+	err = os.WriteFile(marker, []byte(manifest.SHA1), 0644)
+	if err != nil {
+		return "", err
+	}
+	return comp.GetJavaExecutable(dir), nil
+}
+
+/* PRIVATE REGION */
+
+// installRuntimeFile downloads the file through downloadFile, so that an interrupted download is resumed and
+// never left in place of the target. Compressed files are decompressed into a part file renamed once verified.
+func installRuntimeFile(ctx context.Context, file javaRuntimeFile, target string) error {
+	raw := file.Downloads.Raw
+	if checkSHA1Hash(target, raw.SHA1) {
+		installProgress.add(raw.Size)
+		return nil
+	}
+	var mode os.FileMode = 0644
+	if file.Executable {
+		mode = 0755
+	}
+
+	compressed := file.Downloads.LZMA
+	if compressed.Url == "" {
+		err := downloadFile(ctx, raw.Url, target, raw.SHA1, raw.Size)
+		if err != nil {
+			return err
+		}
+		return os.Chmod(target, mode)
+	}
+
+	archive := target + ".lzma"
+	defer os.Remove(archive)
+	err := downloadFile(ctx, compressed.Url, archive, compressed.SHA1, compressed.Size)
+	if err != nil {
+		return err
+	}
+	err = decompressRuntimeFile(archive, target+partSuffix, raw.SHA1, mode)
+	if err != nil {
+		_ = os.Remove(target + partSuffix)
+		return err
+	}
+	// the progress expects the raw size, the compressed bytes were counted by the download
+	installProgress.add(raw.Size - compressed.Size)
+	return os.Rename(target+partSuffix, target)
+}
+
+// decompressRuntimeFile decompresses the lzma archive into path, which has to match hash
+func decompressRuntimeFile(archive string, path string, hash string, mode os.FileMode) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	body, err := lzma.NewReader(f)
+	if err != nil {
+		return err
+	}
+
+	h, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer h.Close()
+	sum := sha1.New()
+	_, err = io.Copy(io.MultiWriter(h, sum), body)
+	if err != nil {
+		return err
+	}
+	if fmt.Sprintf("%x", sum.Sum(nil)) != hash {
+		return errors.New("checksum mismatch of " + path)
+	}
+	return os.Chmod(path, mode)
+}
+
+// javaRuntimePlatforms maps the environments Mojang publishes java runtimes for to their key in the manifest
+var javaRuntimePlatforms = map[string]string{
+	"windows/x86":    "windows-x86",
+	"windows/x86_64": "windows-x64",
+	"windows/arm64":  "windows-arm64",
+	"osx/x86_64":     "mac-os",
+	"osx/arm64":      "mac-os-arm64",
+	"linux/x86":      "linux-i386",
+	"linux/x86_64":   "linux",
+}
+
+// javaRuntimePlatform returns the platform key of the java-runtime manifest for the environment
+func javaRuntimePlatform(env Environment) (string, error) {
+	platform, ok := javaRuntimePlatforms[env.OS+"/"+env.Arch]
+	if !ok {
+		return "", errors.Errorf("no java runtime is published for %s %s, pin a java installation for the profile", env.OS, env.Arch)
+	}
+	return platform, nil
+}
@@ -0,0 +1,107 @@
+package manager
+
+import (
+	"encoding/json"
+	"launcher/manager/comp"
+	"os"
+)
+
+// Install steps, in the order they run. Every step is idempotent, so an interrupted one can simply run again.
+const (
+	StepVersion    = "version"     // vanilla version JSON
+	StepJava       = "java"        // java runtime
+	StepLoader     = "loader"      // loader profile
+	StepClient     = "client"      // client jar and logging configuration
+	StepAssetIndex = "asset_index" // asset index
+	StepAssets     = "assets"      // asset objects
+	StepLibraries  = "libraries"   // libraries
+)
+
+var installSteps = []string{StepVersion, StepJava, StepLoader, StepClient, StepAssetIndex, StepAssets, StepLibraries}
+
+// InstallJournal records the completed steps of the last install, so that an interrupted one can be resumed
+type InstallJournal struct {
+	Version   string   `json:"version"`
+	Loader    string   `json:"loader"`  // kind of the loader
+	Profile   string   `json:"profile"` // id of the loader profile, set by the loader step
+	Done      []string `json:"done"`
+	Completed bool     `json:"completed"`
+}
+
+// ReadInstallJournal returns the journal of the last install, an error when no install was recorded
+func ReadInstallJournal() (InstallJournal, error) {
+	var j InstallJournal
+	b, err := os.ReadFile(comp.GetInstallJournalPath())
+	if err != nil {
+		return InstallJournal{}, err
+	}
+	err = json.Unmarshal(b, &j)
+	if err != nil {
+		return InstallJournal{}, err
+	}
+	return j, nil
+}
+
+// IsDone reports whether the step completed
+func (j *InstallJournal) IsDone(step string) bool {
+	for _, s := range j.Done {
+		if s == step {
+			return true
+		}
+	}
+	return false
+}
+
+// PendingSteps returns the steps which did not complete yet
+func (j *InstallJournal) PendingSteps() []string {
+	var steps []string
+	for _, step := range installSteps {
+		if !j.IsDone(step) {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+/* PRIVATE REGION */
+
+// resumeJournal returns the journal of an interrupted install of version with the loader kind, or a new one
+func resumeJournal(version string, kind string) InstallJournal {
+	j, err := ReadInstallJournal()
+	if err != nil || j.Version != version || j.Loader != kind || j.Completed || (j.IsDone(StepLoader) && j.Profile == "") {
+		return InstallJournal{Version: version, Loader: kind}
+	}
+	return j
+}
+
+// markDone records the step as completed
+func (j *InstallJournal) markDone(step string) error {
+	if !j.IsDone(step) {
+		j.Done = append(j.Done, step)
+	}
+	return j.write()
+}
+
+// complete records the install as completed
+func (j *InstallJournal) complete() error {
+	j.Completed = true
+	return j.write()
+}
+
+func (j *InstallJournal) write() error {
+	b, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(comp.GetLauncherRoot(), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	// written to a temporary file first, so that a crash never leaves a truncated journal
+	tmp := comp.GetInstallJournalPath() + ".tmp"
+	err = os.WriteFile(tmp, b, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, comp.GetInstallJournalPath())
+}
@@ -1,15 +1,17 @@
 package manager
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"github.com/pkg/errors"
 	"io/ioutil"
 	"launcher/logging"
 	"launcher/manager/comp"
+	"launcher/network"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -18,14 +20,16 @@ type LauncherHandle struct {
 }
 
 type LauncherProfile struct {
-	Name      string
-	Config    string
-	JAR       string
-	Manifest  Manifest
-	Version   Version
-	LogCfg    string
-	assets    map[string]Asset
-	libraries []Library
+	Name          string
+	Config        string
+	JAR           string
+	Manifest      Manifest
+	Version       Version
+	LogCfg        string
+	Loader        string // kind of the loader, empty for vanilla profiles
+	LoaderVersion string // build of the loader
+	assets        AssetIndex
+	libraries     []Library
 }
 
 type LauncherAuth struct {
@@ -39,6 +43,12 @@ type LauncherClientSettings struct {
 	Width   int    `json:"width"`
 	Height  int    `json:"height"`
 	JvmArgs string `json:"jvm_args"`
+	Version string `json:"version"`
+	Loader  string `json:"loader"` // loader kind installed by InstallGame, fabric when empty
+	// JavaHomes pins a java installation per profile name, profiles without one use the managed runtime
+	JavaHomes map[string]string `json:"java_homes"`
+	Mirrors   MirrorSettings    `json:"mirrors"`
+	Network   network.Config    `json:"network"`
 }
 
 func InitLauncher() (LauncherHandle, error) {
@@ -49,26 +59,50 @@ func InitLauncher() (LauncherHandle, error) {
 	return LauncherHandle{mf}, nil
 }
 
+// RenameProfile moves the settings pinned to the profile name from to its new name to, as changing the loader renames
+func (s *LauncherClientSettings) RenameProfile(from string, to string) {
+	home, ok := s.JavaHomes[from]
+	if !ok || from == to {
+		return
+	}
+	delete(s.JavaHomes, from)
+	s.JavaHomes[to] = home
+}
+
 func Explore() []LauncherProfile {
 	var profiles []LauncherProfile
 	dir, _ := ioutil.ReadDir(filepath.Join(comp.GetLauncherRoot(), "versions"))
+	if len(dir) == 0 {
+		return profiles
+	}
+
+	mf, err := GetManifest()
+	if err != nil {
+		logging.Logger.Warning("Failed to fetch manifest, caused by: " + err.Error())
+	}
 
 	for _, profile := range dir {
 		if profile.IsDir() {
-			mf, _ := GetManifest()
-			ver, _ := mf.GetVersion(GlobalMinecraftVersion) //TODO version
+			ver, err := LoadVersion(profile.Name())
+			if err != nil {
+				logging.Logger.Error(fmt.Sprintf("Failed to load version of profile %s, caused by: %s", profile.Name(), err.Error()))
+				continue
+			}
 			assets, err := ver.GetAssets()
 			if err == nil {
+				loader, loaderVersion := ver.GetLoader()
 				profiles = append(
 					profiles, LauncherProfile{
-						Name:      profile.Name(),
-						Config:    filepath.Join(comp.GetLauncherRoot(), "versions", profile.Name(), profile.Name()+".json"),
-						JAR:       filepath.Join(comp.GetLauncherRoot(), "versions", profile.Name(), profile.Name()+".jar"),
-						Manifest:  mf,
-						Version:   ver,
-						LogCfg:    filepath.Join(comp.GetLogCfgsPath(), ver.Logging.Client.File.Url),
-						assets:    assets,
-						libraries: ver.Libraries,
+						Name:          profile.Name(),
+						Config:        GetVersionFilePath(profile.Name()),
+						JAR:           GetVersionJARPath(ver.Jar),
+						Manifest:      mf,
+						Version:       ver,
+						LogCfg:        filepath.Join(comp.GetLogCfgsPath(), ver.Logging.Client.File.ID),
+						Loader:        loader,
+						LoaderVersion: loaderVersion,
+						assets:        assets,
+						libraries:     ver.Libraries,
 					})
 			} else {
 				logging.Logger.Error(fmt.Sprintf("Failed to download assets for profile %s", profile.Name()))
@@ -82,58 +116,37 @@ func Explore() []LauncherProfile {
 // VerifyAssets verifies the game assets, and returns the names of missing or corrupt ones
 func (a *LauncherProfile) VerifyAssets() []string {
 	var names []string
-	for name, a := range a.assets {
-		if _, err := os.Stat(filepath.Join(comp.GetAssetsPath(), "objects", a.Hash[0:2], a.Hash)); err == os.ErrNotExist {
-			fmt.Println(name + " missing ")
+	for name, a := range a.assets.Objects {
+		if !checkSHA1Hash(a.GetObjectPath(), a.Hash) {
 			names = append(names, name)
-		} else {
-			if !checkSHA1Hash(filepath.Join(comp.GetAssetsPath(), "objects", a.Hash[0:2], a.Hash), a.Hash) {
-				fmt.Println(name + " hash bad ")
-				names = append(names, name)
-			}
 		}
 	}
-
+	sort.Strings(names)
 	return names
 }
 
 // VerifyLibraries verifies the game libraries, and returns the names of missing or corrupt ones
 func (a *LauncherProfile) VerifyLibraries() []string {
 	var names []string
+	env := CurrentEnvironment()
 	for _, a := range a.libraries {
-		cont := true
-		for _, rule := range a.Rules {
-			if !rule.Complies() {
-				cont = false
-			}
+		if !EvaluateRules(a.Rules, env) {
+			continue
 		}
-		if cont {
-			if _, err := os.Stat(filepath.Join(comp.GetLibraryPath(), a.Downloads.Artifact.Path)); err == os.ErrNotExist {
-				fmt.Println(a.Name + " missing ")
+		for _, artifact := range a.GetArtifacts(env) {
+			if !checkFile(filepath.Join(comp.GetLibraryPath(), artifact.Path), artifact.SHA1) {
 				names = append(names, a.Name)
-			} else {
-				if !checkSHA1Hash(filepath.Join(comp.GetLibraryPath(), a.Downloads.Artifact.Path), a.Downloads.Artifact.SHA1) {
-					fmt.Println(a.Name + " hash bad ")
-					names = append(names, a.Name)
-				}
+				break
 			}
 		}
 	}
 	return names
 }
 
-func (a *LauncherProfile) InstallMinecraft() error {
-	h, err := os.Open(a.JAR)
-	if err != nil {
-		return err
-	}
-	s, err := h.Stat()
-	if err != nil {
-		return err
-	}
-
-	if s.Size() == 0 {
-		err := installMinecraft(a.JAR, a.Version)
+// InstallMinecraft downloads the client jar of the profile, unless a valid one is already present
+func (a *LauncherProfile) InstallMinecraft(ctx context.Context) error {
+	if !checkFile(a.JAR, a.Version.Downloads["client"].SHA1) {
+		err := installMinecraft(ctx, a.JAR, a.Version)
 		if err != nil {
 			return err
 		}
@@ -142,87 +155,113 @@ func (a *LauncherProfile) InstallMinecraft() error {
 	return nil
 }
 
-func CreateProfile(kind string) error {
-	if kind == "fabric-latest" {
-		//TODO this kind shit
-	}
-
-	return InstallTheOnlyProfile(comp.GetLauncherRoot())
+// CreateProfile installs a new profile of the loader kind for the given minecraft version id
+func CreateProfile(ctx context.Context, version string, kind string) error {
+	return InstallProfile(ctx, version, kind)
 }
 
-func (a *LauncherProfile) Launch(auth LauncherAuth, settings LauncherClientSettings) error {
-	if err := checkJava(); err != nil {
+// Launch repairs the broken files of the profile, ctx cancelling the repair, and runs the game until it exits
+func (a *LauncherProfile) Launch(ctx context.Context, auth LauncherAuth, settings LauncherClientSettings) error {
+	java, err := a.resolveJava(settings)
+	if err != nil {
 		return err
 	}
 
-	if len(a.VerifyAssets())+len(a.VerifyLibraries()) != 0 {
-		logging.Logger.Fatal("failed to verify game files, please reinstall")
-		return errors.New("failed to verify game files, please reinstall")
+	report, err := a.Repair(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "failed to repair game files")
 	}
-	parseFabricManifest := func() map[string]interface{} {
-		h, err := os.Open(a.Config)
-		if err != nil {
-			return map[string]interface{}{} // ignore non existing config
-		}
-		defer h.Close()
-
-		var data map[string]interface{}
-		b, _ := ioutil.ReadAll(h)
-		_ = json.Unmarshal(b, &data)
-		return data
+	if !report.IsValid() {
+		logging.Logger.Warning(fmt.Sprintf("Repaired %d assets and %d libraries of profile %s", len(report.Assets), len(report.Libraries), a.Name))
 	}
-
-	toPath := func(s string) string {
-		seg := strings.Split(s, ":")
-		pkg := strings.Split(seg[0], ".")
-		return filepath.Join(comp.GetLibraryPath(), filepath.Join(pkg...), seg[len(seg)-2], seg[len(seg)-1], seg[len(seg)-2]+"-"+seg[len(seg)-1]+".jar")
+	if a.Version.MainClass == "" {
+		return errors.New("version " + a.Version.ID + " does not declare a main class")
 	}
 
-	fabricmf := parseFabricManifest()
-	libs := fabricmf["libraries"].([]interface{})
-	var extra []string
-	for _, l := range libs {
-		extra = append(extra, toPath(l.(map[string]interface{})["name"].(string)))
+	env := CurrentEnvironment()
+	err = os.MkdirAll(comp.GetNativesPath(), os.ModePerm)
+	if err != nil {
+		return errors.WithMessage(err, "failed to create natives directory")
+	}
+	natives, err := os.MkdirTemp(comp.GetNativesPath(), a.Version.ID+"-")
+	if err != nil {
+		return errors.WithMessage(err, "failed to create natives directory")
+	}
+	defer os.RemoveAll(natives)
+	err = a.Version.ExtractNatives(natives, env)
+	if err != nil {
+		return err
 	}
-
-	version := GlobalMinecraftVersion //TODO implement version
 
 	extraJvmArgs := strings.Split(settings.JvmArgs, " ")
 
 	jvm, game := a.Version.CreateCommandLine(a.JAR, LaunchPlaceholders{
-		NativesDirectory: ".",
+		NativesDirectory: natives,
 		LauncherName:     "Genecraft Launcher",
 		LauncherVersion:  "1.0",
 		Username:         auth.Username,
-		Version:          version,
+		Version:          a.Version.ID,
 		GameDir:          comp.GetLauncherRoot(),
 		AssetDir:         comp.GetAssetsPath(),
-		AssetIndex:       version,
+		GameAssets:       a.assets.GetGameAssetsPath(a.Version.AssetIndex.ID, comp.GetLauncherRoot()),
+		AssetIndex:       a.Version.AssetIndex.ID,
 		UUID:             auth.UUID,
 		AccessToken:      auth.AccessToken,
 		ClientID:         "",
 		XUID:             "",
 		UserType:         "msa",
-		VersionType:      "release",
+		VersionType:      a.Version.Type,
 		LogCfgPath:       a.LogCfg,
+		AuthSession:      "token:" + auth.AccessToken + ":" + auth.UUID,
+		UserProperties:   "{}",
+		LibraryDirectory: comp.GetLibraryPath(),
+		ClasspathSep:     string(comp.GetSeparator()),
 	}, LaunchOptions{
 		Width:  settings.Width,
 		Height: settings.Height,
 		MaxRam: settings.Memory,
+		Env:    env,
 	},
-		extra, extraJvmArgs)
+		nil, extraJvmArgs)
 
-	args := append(jvm, fabricmf["mainClass"].(string))
+	args := append(jvm, a.Version.MainClass)
 	args = append(args, game...)
-	cmd := exec.Command("java", args...)
+	cmd := exec.Command(java, args...)
 	cmd.Dir = comp.GetLauncherRoot()
 	cmd.Stdout = nil
-	fmt.Println(cmd.String())
+	command := cmd.String()
+	if auth.AccessToken != "" {
+		command = strings.Replace(command, auth.AccessToken, "<access token>", -1) // also hides the auth session
+	}
+	logging.Logger.Print("Launching " + command)
 
-	//TODO: log command
-	err := cmd.Run()
+	err = cmd.Run()
 	if err != nil {
 		logging.Logger.Fatal("failed to launch game, cause by: " + err.Error())
 	}
 	return err
 }
+
+/* PRIVATE REGION */
+
+// resolveJava returns the java executable the profile is launched with
+func (a *LauncherProfile) resolveJava(settings LauncherClientSettings) (string, error) {
+	required := a.Version.GetJavaVersion()
+	home := settings.JavaHomes[a.Name]
+	if home == "" {
+		java, err := InstallJavaRuntime(context.Background(), required)
+		if err != nil {
+			return "", errors.WithMessage(err, "failed to install java runtime")
+		}
+		return java, nil
+	}
+
+	java, err := InspectJava(home)
+	if err != nil {
+		return "", errors.WithMessage(err, "invalid java installation pinned for profile "+a.Name)
+	}
+	if java.Major < required.MajorVersion {
+		return "", errors.Errorf("java %d pinned for profile %s, but at least java %d is required", java.Major, a.Name, required.MajorVersion)
+	}
+	return java.Executable, nil
+}
@@ -0,0 +1,80 @@
+package manager
+
+import (
+	"os"
+	"sort"
+	"time"
+)
+
+// Version types used by the manifest
+const (
+	VersionRelease  = "release"
+	VersionSnapshot = "snapshot"
+	VersionOldBeta  = "old_beta"
+	VersionOldAlpha = "old_alpha"
+)
+
+type VersionInfo struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	ReleaseTime string `json:"release_time"`
+	Installed   bool   `json:"installed"`
+}
+
+// ListVersions lists the manifest versions of the given types, all of them when none is given,
+// newest first, marking the ones installed locally
+func ListVersions(types ...string) ([]VersionInfo, error) {
+	mf, err := GetManifest()
+	if err != nil {
+		return nil, err
+	}
+	return mf.ListVersions(types...), nil
+}
+
+// ListVersions lists the versions of the given types, all of them when none is given, newest first
+func (mf *Manifest) ListVersions(types ...string) []VersionInfo {
+	allowed := make(map[string]bool)
+	for _, t := range types {
+		allowed[t] = true
+	}
+
+	var entries []ManifestVersion
+	for _, v := range mf.Versions {
+		if len(allowed) == 0 || allowed[v.Type] {
+			entries = append(entries, v)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return parseReleaseTime(entries[i].ReleaseTime).After(parseReleaseTime(entries[j].ReleaseTime))
+	})
+
+	ret := make([]VersionInfo, 0, len(entries))
+	for _, v := range entries {
+		ret = append(ret, VersionInfo{
+			ID:          v.ID,
+			Type:        v.Type,
+			ReleaseTime: v.ReleaseTime,
+			Installed:   IsVersionInstalled(v.ID),
+		})
+	}
+	return ret
+}
+
+// IsVersionInstalled reports whether the version JSON and the client jar of the version are present
+func IsVersionInstalled(id string) bool {
+	if _, err := os.Stat(GetVersionFilePath(id)); err != nil {
+		return false
+	}
+	s, err := os.Stat(GetVersionJARPath(id))
+	return err == nil && s.Size() > 0
+}
+
+/* PRIVATE REGION */
+
+func parseReleaseTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,167 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"launcher/logging"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Mod loaders a profile can be installed with
+const (
+	LoaderFabric   = "fabric"
+	LoaderQuilt    = "quilt"
+	LoaderForge    = "forge"
+	LoaderNeoForge = "neoforge"
+)
+
+// loaderLibraries identifies the loader of a profile by the group and artifact of its main library
+var loaderLibraries = map[string]string{
+	"net.fabricmc:fabric-loader": LoaderFabric,
+	"org.quiltmc:quilt-loader":   LoaderQuilt,
+	"net.minecraftforge:forge":   LoaderForge,
+	"net.neoforged:neoforge":     LoaderNeoForge,
+}
+
+// GetLoaderVersions returns the builds of the loader kind available for the minecraft version, newest first
+func GetLoaderVersions(ctx context.Context, kind string, version string) ([]string, error) {
+	var builds []string
+	switch kind {
+	case LoaderFabric:
+		loaders, err := GetFabricLoaders(ctx, version)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range loaders {
+			builds = append(builds, l.Loader.Version)
+		}
+		return builds, nil
+	case LoaderQuilt:
+		loaders, err := GetQuiltLoaders(ctx, version)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range loaders {
+			builds = append(builds, l.Loader.Version)
+		}
+		return builds, nil
+	case LoaderForge:
+		return GetForgeVersions(ctx, version)
+	case LoaderNeoForge:
+		return GetNeoForgeVersions(ctx, version)
+	}
+	return nil, errors.Errorf("unknown loader %s", kind)
+}
+
+// GetLoader returns the kind and build of the loader of the version, both empty for vanilla versions
+func (v *Version) GetLoader() (string, string) {
+	for _, lib := range v.Libraries {
+		seg := strings.Split(strings.Split(lib.Name, "@")[0], ":")
+		if len(seg) < 3 {
+			continue
+		}
+		if kind, ok := loaderLibraries[seg[0]+":"+seg[1]]; ok {
+			return kind, seg[2]
+		}
+	}
+	return "", ""
+}
+
+// ChangeLoader upgrades or downgrades the loader of the profile to build and switches the profile to it.
+// Only the loader profile and its libraries change, the mods, saves and configuration of the game are kept.
+func (a *LauncherProfile) ChangeLoader(ctx context.Context, build string) error {
+	if a.Loader == "" {
+		return errors.New("profile " + a.Name + " has no loader")
+	}
+	if build == a.LoaderVersion {
+		return nil
+	}
+	vanilla, err := LoadVersion(a.Version.GetMinecraftVersion())
+	if err != nil {
+		return errors.WithMessage(err, "failed to load version data")
+	}
+
+	installProgress.begin(0)
+	defer installProgress.end()
+	installProgress.setStage(StageLoader, "Installing "+a.Loader+" "+build)
+	id, err := installLoader(ctx, a.Loader, vanilla, build)
+	if err != nil {
+		return errors.WithMessage(err, "failed to install "+a.Loader)
+	}
+	ver, err := LoadVersion(id)
+	if err != nil {
+		return errors.WithMessage(err, "failed to load version data")
+	}
+	env := CurrentEnvironment()
+	installProgress.expect(plannedSize(ver, env, InstallJournal{Done: []string{StepClient, StepAssets}}))
+	installProgress.setStage(StageLibraries, "Downloading libraries")
+	_, err = downloadLibraries(ctx, ver)
+	if err != nil {
+		return errors.WithMessage(err, "failed to download libraries")
+	}
+
+	if id != a.Name {
+		err = os.RemoveAll(filepath.Dir(GetVersionFilePath(a.Name)))
+		if err != nil {
+			return errors.WithMessage(err, "failed to remove previous loader profile")
+		}
+		// an interrupted install of the profile resumes with the new loader
+		if j, err := ReadInstallJournal(); err == nil && j.Profile == a.Name {
+			j.Profile = id
+			_ = j.write()
+		}
+	}
+	a.Name = id
+	a.Config = GetVersionFilePath(id)
+	a.Version = ver
+	a.libraries = ver.Libraries
+	a.Loader, a.LoaderVersion = ver.GetLoader()
+	_, err = UpdateStoreIndex()
+	if err != nil {
+		logging.Logger.Warning("Failed to update the store index, caused by: " + err.Error())
+	}
+	installProgress.complete()
+	return nil
+}
+
+/* PRIVATE REGION */
+
+// installLoader writes the profile of the loader of kind for the vanilla version and returns its id.
+// An empty loader selects the newest stable build.
+func installLoader(ctx context.Context, kind string, vanilla Version, loader string) (string, error) {
+	switch kind {
+	case LoaderFabric:
+		return installFabric(ctx, vanilla.ID, loader)
+	case LoaderQuilt:
+		return installQuilt(ctx, vanilla.ID, loader)
+	case LoaderForge, LoaderNeoForge:
+		return installForge(ctx, kind, vanilla, loader)
+	}
+	return "", errors.Errorf("unknown loader %s", kind)
+}
+
+// writeLoaderProfile validates the loader profile JSON inheriting from the minecraft version,
+// writes it into its profile directory and returns its id
+func writeLoaderProfile(b []byte, version string) (string, error) {
+	var ver Version
+	err := json.Unmarshal(b, &ver)
+	if err != nil {
+		return "", errors.WithMessage(err, "invalid loader profile")
+	}
+	if ver.ID == "" || ver.InheritsFrom != version {
+		return "", errors.Errorf("loader profile %s does not inherit from %s", ver.ID, version)
+	}
+	path := GetVersionFilePath(ver.ID)
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+	err = os.WriteFile(path, b, 0644)
+	if err != nil {
+		return "", err
+	}
+	return ver.ID, nil
+}
@@ -1,38 +1,44 @@
 package manager
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/pkg/errors"
-	"io"
 	"launcher/manager/comp"
-	"net/http"
+	"launcher/manager/maven"
 	"path/filepath"
 	"reflect"
-	"runtime"
 	"strconv"
 	"strings"
 )
 
-const versionManifestUrl = "https://launchermeta.mojang.com/mc/game/version_manifest.json"
-
 type Manifest struct {
 	Latest struct {
 		Release  string `json:"release"`
 		Snapshot string `json:"snapshot"`
 	} `json:"latest"`
-	Versions []struct {
-		ID          string `json:"id"`
-		Type        string `json:"type"`
-		Url         string `json:"url"`
-		Time        string `json:"time"`
-		ReleaseTime string `json:"release_time"`
-	} `json:"versions"`
+	Versions []ManifestVersion `json:"versions"`
+}
+
+type ManifestVersion struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	Url         string `json:"url"`
+	SHA1        string `json:"sha1"`
+	Time        string `json:"time"`
+	ReleaseTime string `json:"releaseTime"`
 }
 type Version struct {
-	Arguments struct {
-		JVM  []any `json:"jvm"`
-		Game []any `json:"game"`
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	InheritsFrom string `json:"inheritsFrom"`
+	MainClass    string `json:"mainClass"`
+	// MinecraftArguments holds the game arguments of versions older than 1.13, which predate Arguments
+	MinecraftArguments string `json:"minecraftArguments"`
+	Arguments          struct {
+		JVM  []Argument `json:"jvm"`
+		Game []Argument `json:"game"`
 	} `json:"arguments"`
 	AssetIndex struct {
 		ID        string `json:"id"`
@@ -41,14 +47,12 @@ type Version struct {
 		TotalSize int    `json:"totalSize"`
 		Url       string `json:"url"`
 	} `json:"assetIndex"`
-	Downloads map[string]struct {
-		SHA1 string `json:"sha1"`
-		Size int64  `json:"size"`
-		Url  string `json:"url"`
-	} `json:"downloads"`
-	Assets    string `json:"assets"`
-	Libraries []Library
-	Logging   struct {
+	JavaVersion JavaVersion         `json:"javaVersion"`
+	Downloads   map[string]Artifact `json:"downloads"`
+	Assets      string              `json:"assets"`
+	Jar         string              `json:"jar"`
+	Libraries   []Library           `json:"libraries"`
+	Logging     struct {
 		Client struct {
 			Argument string `json:"argument"`
 			File     struct {
@@ -59,28 +63,40 @@ type Version struct {
 			} `json:"file"`
 		} `json:"client"`
 	} `json:"logging"`
+
+	baseVersion string
 }
 
 type Library struct {
 	Downloads struct {
-		Artifact struct {
-			Path string
-			SHA1 string
-			Size int64
-			Url  string
-		}
-	}
-	Name  string `json:"name"`
-	Rules []Rule `json:"rules"`
+		Artifact    Artifact            `json:"artifact"`
+		Classifiers map[string]Artifact `json:"classifiers"`
+	} `json:"downloads"`
+	Name    string            `json:"name"`
+	Url     string            `json:"url"`
+	SHA1    string            `json:"sha1"` // declared by loader metadata for maven libraries without downloads
+	Size    int64             `json:"size"`
+	Rules   []Rule            `json:"rules"`
+	Natives map[string]string `json:"natives"` // native classifier per os, may contain ${arch}
+	Extract struct {
+		Exclude []string `json:"exclude"`
+	} `json:"extract"`
 }
-type Rule struct {
-	Action string `json:"action"`
-	OS     struct {
-		Name    string `json:"name"`
-		Version string `json:"version"`
-		Arch    string `json:"arch"`
-	} `json:"os"`
+
+type Artifact struct {
+	Path string `json:"path"`
+	SHA1 string `json:"sha1"`
+	Size int64  `json:"size"`
+	Url  string `json:"url"`
+}
+
+// AssetIndex lists the assets of a version, old indexes are flagged to use the legacy layouts
+type AssetIndex struct {
+	Objects        map[string]Asset `json:"objects"`
+	Virtual        bool             `json:"virtual"`          // assets are read from assets/virtual/<id>
+	MapToResources bool             `json:"map_to_resources"` // assets are read from <gameDir>/resources
 }
+
 type Asset struct {
 	Hash string `json:"hash"`
 	Size int64  `json:"size"`
@@ -102,17 +118,25 @@ type LaunchPlaceholders struct {
 	UserType         string `placeholder:"user_type"`
 	VersionType      string `placeholder:"version_type"`
 	LogCfgPath       string `placeholder:"path"`
+	ResolutionWidth  string `placeholder:"resolution_width"`
+	ResolutionHeight string `placeholder:"resolution_height"`
+	AuthSession      string `placeholder:"auth_session"`
+	UserProperties   string `placeholder:"user_properties"`
+	GameAssets       string `placeholder:"game_assets"`
+	LibraryDirectory string `placeholder:"library_directory"`
+	ClasspathSep     string `placeholder:"classpath_separator"`
 }
 
 type LaunchOptions struct {
 	Width  int
 	Height int
 	MaxRam int
+	Env    Environment // platform the command line is built for, features are derived from the options
 }
 
 func GetManifest() (Manifest, error) {
 	var mf Manifest
-	err := receiveJSONObject(versionManifestUrl, &mf)
+	err := receiveJSONObject(context.Background(), versionManifestUrl(), &mf)
 	if err != nil {
 		return Manifest{}, err
 	}
@@ -123,28 +147,53 @@ func (mf *Manifest) GetVersion(version string) (Version, error) {
 	for i := range mf.Versions {
 		if mf.Versions[i].ID == version {
 			var ret Version
-			err := receiveJSONObject(mf.Versions[i].Url, &ret)
+			err := receiveVerifiedJSONObject(context.Background(), mf.Versions[i].Url, mf.Versions[i].SHA1, &ret)
 			if err != nil {
-				return Version{}, errors.WithMessage(err, "failed to download latest version data")
+				return Version{}, errors.WithMessage(err, "failed to download version data")
 			}
 			return ret, nil
 		}
 	}
-	return Version{}, errors.Errorf("latest release version \"%s\" not found in the manifest file", mf.Latest.Release)
+	return Version{}, errors.Errorf("version \"%s\" not found in the manifest file", version)
+}
+
+// GetMinecraftVersion returns the id of the vanilla version at the root of the inheritance chain
+func (v *Version) GetMinecraftVersion() string {
+	if v.baseVersion != "" {
+		return v.baseVersion
+	}
+	if v.InheritsFrom != "" {
+		return v.InheritsFrom
+	}
+	return v.ID
 }
-func (v *Version) GetAssets() (map[string]Asset, error) {
-	var ret assetIndex
-	err := receiveJSONObject(v.AssetIndex.Url, &ret)
+
+// GetAssets returns the asset index of the version
+func (v *Version) GetAssets() (AssetIndex, error) {
+	var ret AssetIndex
+	err := receiveVerifiedJSONObject(context.Background(), v.AssetIndex.Url, v.AssetIndex.SHA1, &ret)
 	if err != nil {
-		return map[string]Asset{}, err
+		return AssetIndex{}, err
 	}
-	return ret.Objects, nil
+	return ret, nil
 }
 
 func (v *Version) CreateCommandLine(gameJar string, placeholders LaunchPlaceholders, opts LaunchOptions, extraLibs []string, extraArgs []string) ([]string, []string) {
 	var jvm []string
 	var game []string
 
+	env := opts.Env
+	if opts.Width > 0 && opts.Height > 0 {
+		env = env.WithFeature(FeatureCustomResolution, true)
+		placeholders.ResolutionWidth = strconv.Itoa(opts.Width)
+		placeholders.ResolutionHeight = strconv.Itoa(opts.Height)
+	}
+
+	cp := v.GetLibraryPaths(comp.GetLibraryPath(), env)
+	cp = append(cp, extraLibs...)
+	cp = append(cp, gameJar)
+	classpath := strings.Join(cp, string(comp.GetSeparator()))
+
 	replacePlaceholders := func(s string) string {
 		rpl := func(s string, key string, value string) string {
 			if strings.ContainsRune(value, ' ') {
@@ -165,44 +214,28 @@ func (v *Version) CreateCommandLine(gameJar string, placeholders LaunchPlacehold
 		for i := 0; i < r.NumField(); i++ {
 			s = rpl(s, t.Field(i).Tag.Get("placeholder"), r.Field(i).Interface().(string))
 		}
-		cp := v.GetLibraryPaths(comp.GetLibraryPath())
-		cp = append(cp, extraLibs...)
-		cp = append(cp, gameJar)
-		return rpl(s, "classpath", strings.Join(cp, string(comp.GetSeparator())))
+		return rpl(s, "classpath", classpath)
 	}
 
-	for _, a := range v.Arguments.JVM {
-		if reflect.TypeOf(a).Kind() == reflect.String {
-			s := replacePlaceholders(a.(string))
-			if s != "" {
-				jvm = append(jvm, s)
+	expand := func(args []Argument) []string {
+		var ret []string
+		for _, a := range args {
+			if !a.Allowed(env) {
+				continue
 			}
-		} else if reflect.TypeOf(a).Kind() == reflect.Map {
-			b, _ := json.Marshal(a.(map[string]interface{})["rules"])
-			var rules []Rule
-			_ = json.Unmarshal(b, &rules)
-			for _, rule := range rules {
-				if rule.Complies() {
-					val := a.(map[string]interface{})["value"]
-					if reflect.TypeOf(val).Kind() == reflect.Slice {
-						for _, str := range val.([]interface{}) {
-							s := replacePlaceholders(str.(string))
-							if s != "" {
-								jvm = append(jvm, s)
-							}
-
-						}
-					} else {
-						s := replacePlaceholders(val.(string))
-						if s != "" {
-							jvm = append(jvm, s)
-						}
-					}
+			for _, value := range a.Value {
+				s := replacePlaceholders(value)
+				if s != "" {
+					ret = append(ret, s)
 				}
 			}
 		}
+		return ret
 	}
 
+	jvmArgs, gameArgs := v.GetArguments()
+	jvm = expand(jvmArgs)
+
 	if len(extraArgs) > 0 {
 		for _, arg := range extraArgs {
 			if arg != "" {
@@ -211,7 +244,6 @@ func (v *Version) CreateCommandLine(gameJar string, placeholders LaunchPlacehold
 		}
 	}
 
-	jvm = append(jvm, "-DFabricMcEmu=net.minecraft.client.main.Main") //TODO: dynamic ADD FABRIC JVM OPT
 	if opts.MaxRam > 0 {
 		if opts.MaxRam%1024 != 0 {
 			if opts.MaxRam <= 2048 {
@@ -223,81 +255,158 @@ func (v *Version) CreateCommandLine(gameJar string, placeholders LaunchPlacehold
 		jvm = append(jvm, "-Xmx"+strconv.Itoa(opts.MaxRam)+"k")
 	}
 
-	jvm = append(jvm, strings.Replace(v.Logging.Client.Argument, "${path}", filepath.Join(comp.GetLogCfgsPath(), v.Logging.Client.File.ID), -1))
+	if v.Logging.Client.Argument != "" {
+		jvm = append(jvm, strings.Replace(v.Logging.Client.Argument, "${path}", filepath.Join(comp.GetLogCfgsPath(), v.Logging.Client.File.ID), -1))
+	}
 
-	for _, a := range v.Arguments.Game {
-		if reflect.TypeOf(a).Kind() == reflect.String {
-			s := replacePlaceholders(a.(string))
-			if s != "" {
-				game = append(game, s)
-			}
-		}
+	game = expand(gameArgs)
+	if v.MinecraftArguments != "" && len(v.Arguments.Game) == 0 && env.Features[FeatureCustomResolution] {
+		game = append(game, "--width", placeholders.ResolutionWidth, "--height", placeholders.ResolutionHeight)
 	}
+	return jvm, game
+}
 
-	if opts.Width > 0 && opts.Height > 0 {
-		game = append(game, "--width "+strconv.Itoa(opts.Width))
-		game = append(game, "--height "+strconv.Itoa(opts.Height))
+// GetArguments returns the jvm and game arguments of the version. Versions using the legacy minecraftArguments
+// string get the jvm arguments the official launcher synthesizes for them.
+func (v *Version) GetArguments() ([]Argument, []Argument) {
+	jvm := v.Arguments.JVM
+	game := v.Arguments.Game
+	if v.MinecraftArguments != "" {
+		if len(jvm) == 0 {
+			jvm = legacyJVMArguments
+		}
+		if len(game) == 0 {
+			for _, arg := range strings.Fields(v.MinecraftArguments) {
+				game = append(game, Argument{Value: []string{arg}})
+			}
+		}
 	}
 	return jvm, game
 }
 
-func (v *Version) GetLibraryPaths(dir string) []string {
+// GetLibraryPaths returns the paths of the libraries required in the environment
+func (v *Version) GetLibraryPaths(dir string, env Environment) []string {
 	var ret []string
 	for _, library := range v.Libraries {
-		var cont bool = true
-		for _, rule := range library.Rules {
-			if !rule.Complies() {
-				cont = false
-			}
-		}
-		if cont {
-			ret = append(ret, filepath.Join(dir, library.Downloads.Artifact.Path))
+		if EvaluateRules(library.Rules, env) && library.HasArtifact() {
+			ret = append(ret, filepath.Join(dir, library.GetArtifact().Path))
 		}
 	}
 	return ret
 }
 
-func (r *Rule) Complies() bool {
-	if runtime.GOOS == r.OS.Name {
-		if r.OS.Arch != "" {
-			if runtime.GOARCH == r.OS.Arch {
-				return true //TODO figure out how the fuck architecture check works
-			} else {
-				return false
-			}
-		} else {
-			return true
-		}
-	} else if runtime.GOOS == "darwin" && r.OS.Name == "osx" {
-		if r.OS.Arch != "" {
-			if runtime.GOARCH == r.OS.Arch {
-				return true //TODO figure out how the fuck architecture check works
-			} else {
-				return false
-			}
-		} else {
-			return true
+// GetArtifact returns the library artifact, deriving its path and url from the maven name when the
+// version JSON only lists a repository, as loader profiles and old versions do.
+// A declared artifact without url is generated by a loader installer and keeps its empty url.
+func (l *Library) GetArtifact() Artifact {
+	a := l.Downloads.Artifact
+	declared := a.Path != "" || a.Url != ""
+	if a.Path == "" {
+		a.Path = mavenPath(l.Name)
+	}
+	if !declared {
+		a.Url = l.repositoryUrl(a.Path)
+	}
+	if a.SHA1 == "" {
+		a.SHA1 = l.SHA1
+	}
+	if a.Size == 0 {
+		a.Size = l.Size
+	}
+	return a
+}
+
+// HasArtifact reports whether the library has a classpath artifact, legacy native-only libraries do not
+func (l *Library) HasArtifact() bool {
+	return l.Natives == nil || l.Downloads.Artifact.Path != "" || l.Downloads.Artifact.Url != ""
+}
+
+// GetNativeArtifact returns the native classifier artifact of the library for the environment, if it has one
+func (l *Library) GetNativeArtifact(env Environment) (Artifact, bool) {
+	classifier, ok := l.Natives[env.OS]
+	if !ok {
+		return Artifact{}, false
+	}
+	bits := "64"
+	if env.Arch == "x86" || env.Arch == "arm32" {
+		bits = "32"
+	}
+	classifier = strings.Replace(classifier, "${arch}", bits, -1)
+
+	a := l.Downloads.Classifiers[classifier]
+	if a.Path == "" {
+		if c, err := maven.Parse(l.Name); err == nil {
+			a.Path = filepath.FromSlash(c.WithClassifier(classifier).Path())
 		}
-	} else {
-		return false
 	}
+	if a.Url == "" {
+		a.Url = l.repositoryUrl(a.Path)
+	}
+	return a, true
+}
+
+// repositoryUrl returns the url of path in the library's repository, Mojang's one when none is declared
+func (l *Library) repositoryUrl(path string) string {
+	repository := l.Url
+	if repository == "" {
+		repository = currentEndpoints().Libraries
+	}
+	return strings.TrimSuffix(repository, "/") + "/" + filepath.ToSlash(path)
+}
+
+// GetArtifacts returns every artifact of the library needed in the environment
+func (l *Library) GetArtifacts(env Environment) []Artifact {
+	var ret []Artifact
+	if l.HasArtifact() {
+		ret = append(ret, l.GetArtifact())
+	}
+	if native, ok := l.GetNativeArtifact(env); ok {
+		ret = append(ret, native)
+	}
+	return ret
 }
 
 /* PRIVATE REGION */
-type assetIndex struct {
-	Objects map[string]Asset `json:"objects"`
+
+// legacyJVMArguments are the default jvm arguments for versions using minecraftArguments
+var legacyJVMArguments = mustParseArguments(`[
+	{"rules": [{"action": "allow", "os": {"name": "osx"}}], "value": ["-XstartOnFirstThread"]},
+	{"rules": [{"action": "allow", "os": {"name": "windows"}}], "value": "-XX:HeapDumpPath=MojangTricksIntelDriversForPerformance_javaw.exe_minecraft.exe.heapdump"},
+	{"rules": [{"action": "allow", "os": {"name": "windows", "version": "^10\\."}}], "value": ["-Dos.name=Windows 10", "-Dos.version=10.0"]},
+	{"rules": [{"action": "allow", "os": {"arch": "x86"}}], "value": "-Xss1M"},
+	"-Djava.library.path=${natives_directory}",
+	"-Dminecraft.launcher.brand=${launcher_name}",
+	"-Dminecraft.launcher.version=${launcher_version}",
+	"-cp",
+	"${classpath}"
+]`)
+
+func mustParseArguments(data string) []Argument {
+	var args []Argument
+	if err := json.Unmarshal([]byte(data), &args); err != nil {
+		panic(err)
+	}
+	return args
 }
 
-func receiveJSONObject(address string, a any) error {
-	get, err := http.Get(address)
+func receiveJSONObject(ctx context.Context, address string, a any) error {
+	return receiveVerifiedJSONObject(ctx, address, "", a)
+}
+
+func receiveVerifiedJSONObject(ctx context.Context, address string, hash string, a any) error {
+	b, err := fetchMetadata(ctx, address, hash)
 	if err != nil {
 		return err
 	}
+	if json.Unmarshal(b, a) == nil {
+		return nil
+	}
 
-	b, err := io.ReadAll(get.Body)
+	// the server confirms a corrupt copy without hash as unmodified, so it is dropped and fetched again
+	removeCache(address)
+	b, err = fetchMetadata(ctx, address, hash)
 	if err != nil {
 		return err
 	}
-
 	return json.Unmarshal(b, a)
 }
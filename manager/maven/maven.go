@@ -0,0 +1,68 @@
+package maven
+
+import (
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// Coordinate identifies a maven artifact, written group:artifact:version[:classifier][@extension]
+type Coordinate struct {
+	Group      string
+	Artifact   string
+	Version    string
+	Classifier string // empty for the main artifact
+	Extension  string // jar when empty
+}
+
+// Parse parses a coordinate in the group:artifact:version[:classifier][@extension] notation
+func Parse(name string) (Coordinate, error) {
+	var c Coordinate
+	if i := strings.LastIndex(name, "@"); i >= 0 {
+		name, c.Extension = name[:i], name[i+1:]
+	}
+	seg := strings.Split(name, ":")
+	if len(seg) < 3 || len(seg) > 4 {
+		return Coordinate{}, errors.New("invalid maven coordinate " + name)
+	}
+	for _, s := range seg {
+		if s == "" {
+			return Coordinate{}, errors.New("invalid maven coordinate " + name)
+		}
+	}
+	c.Group, c.Artifact, c.Version = seg[0], seg[1], seg[2]
+	if len(seg) == 4 {
+		c.Classifier = seg[3]
+	}
+	return c, nil
+}
+
+// String returns the coordinate in the notation Parse accepts
+func (c Coordinate) String() string {
+	s := c.Group + ":" + c.Artifact + ":" + c.Version
+	if c.Classifier != "" {
+		s += ":" + c.Classifier
+	}
+	if c.Extension != "" {
+		s += "@" + c.Extension
+	}
+	return s
+}
+
+// WithClassifier returns the artifact of the same version with the classifier
+func (c Coordinate) WithClassifier(classifier string) Coordinate {
+	c.Classifier = classifier
+	return c
+}
+
+// Path returns the slash separated path of the artifact in a repository
+func (c Coordinate) Path() string {
+	ext := c.Extension
+	if ext == "" {
+		ext = "jar"
+	}
+	file := c.Artifact + "-" + c.Version
+	if c.Classifier != "" {
+		file += "-" + c.Classifier
+	}
+	return strings.Replace(c.Group, ".", "/", -1) + "/" + c.Artifact + "/" + c.Version + "/" + file + "." + ext
+}
@@ -0,0 +1,200 @@
+package maven
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"github.com/pkg/errors"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Checksum is the hash of an artifact as published next to it in its repository
+type Checksum struct {
+	Algorithm string // sha1 or sha256
+	Hash      string
+}
+
+// New returns a hash of the algorithm of the checksum, nil for an unknown algorithm
+func (s Checksum) New() hash.Hash {
+	switch s.Algorithm {
+	case "sha1":
+		return sha1.New()
+	case "sha256":
+		return sha256.New()
+	}
+	return nil
+}
+
+// Verify reports whether the file at path matches the checksum
+func (s Checksum) Verify(path string) bool {
+	h := s.New()
+	if h == nil {
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return false
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)) == s.Hash
+}
+
+// Resolver downloads artifacts from maven repositories and verifies them against their checksum files
+type Resolver struct {
+	Client       *http.Client // http.DefaultClient when nil
+	Repositories []string     // tried in order after the repository the artifact declares
+	// Fetch returns the small file at address, like a checksum file, a plain request when nil
+	Fetch func(ctx context.Context, address string) ([]byte, error)
+	// Download stores the file at address in path once it matches sum, a plain request when nil
+	Download func(ctx context.Context, address string, path string, sum Checksum) error
+}
+
+// checksumFiles lists the checksum files tried for an artifact, the strongest first
+var checksumFiles = []struct {
+	algorithm string
+	length    int
+}{
+	{"sha256", 64},
+	{"sha1", 40},
+}
+
+// Checksum returns the checksum published next to the artifact at address
+func (r *Resolver) Checksum(ctx context.Context, address string) (Checksum, error) {
+	var last error
+	for _, c := range checksumFiles {
+		b, err := r.fetch(ctx, address+"."+c.algorithm)
+		if err != nil {
+			if ctx.Err() != nil {
+				return Checksum{}, ctx.Err()
+			}
+			last = err
+			continue
+		}
+		// the hash may be followed by the file name
+		fields := strings.Fields(string(b))
+		if len(fields) == 0 || len(fields[0]) != c.length {
+			last = errors.New("invalid checksum file " + address + "." + c.algorithm)
+			continue
+		}
+		return Checksum{Algorithm: c.algorithm, Hash: strings.ToLower(fields[0])}, nil
+	}
+	return Checksum{}, last
+}
+
+// Resolve stores the artifact in path, taking it from the declared repository or else from the first configured one
+// publishing it. The artifact is verified against the checksum of its repository, a valid file at path is kept.
+// It reports whether the artifact was downloaded.
+func (r *Resolver) Resolve(ctx context.Context, c Coordinate, repository string, path string) (bool, error) {
+	var last error
+	for _, repo := range r.repositories(repository) {
+		address := repo + "/" + c.Path()
+		sum, err := r.Checksum(ctx, address)
+		if err == nil && sum.Verify(path) {
+			return false, nil
+		}
+		if err == nil {
+			err = r.download(ctx, address, path, sum)
+		}
+		if err == nil {
+			return true, nil
+		}
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		last = err
+	}
+	if last == nil {
+		last = errors.New("no repository configured")
+	}
+	return false, errors.WithMessage(last, "failed to resolve "+c.String())
+}
+
+/* PRIVATE REGION */
+
+// repositories returns the declared repository followed by the configured ones, without duplicates
+func (r *Resolver) repositories(declared string) []string {
+	var repos []string
+	seen := make(map[string]bool)
+	for _, repo := range append([]string{declared}, r.Repositories...) {
+		repo = strings.TrimSuffix(repo, "/")
+		if repo != "" && !seen[repo] {
+			seen[repo] = true
+			repos = append(repos, repo)
+		}
+	}
+	return repos
+}
+
+func (r *Resolver) client() *http.Client {
+	if r.Client == nil {
+		return http.DefaultClient
+	}
+	return r.Client
+}
+
+func (r *Resolver) fetch(ctx context.Context, address string) ([]byte, error) {
+	if r.Fetch != nil {
+		return r.Fetch(ctx, address)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", address, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := r.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status %d while fetching %s", res.StatusCode, address)
+	}
+	return io.ReadAll(io.LimitReader(res.Body, 1024))
+}
+
+func (r *Resolver) download(ctx context.Context, address string, path string, sum Checksum) error {
+	if r.Download != nil {
+		return r.Download(ctx, address, path, sum)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", address, nil)
+	if err != nil {
+		return err
+	}
+	res, err := r.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status %d while fetching %s", res.StatusCode, address)
+	}
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	// written to a part file first, so that an interrupted download is never taken for the artifact
+	f, err := os.Create(path + ".part")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, res.Body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && !sum.Verify(path+".part") {
+		err = errors.Errorf("%s does not match its %s checksum", address, sum.Algorithm)
+	}
+	if err != nil {
+		_ = os.Remove(path + ".part")
+		return err
+	}
+	return os.Rename(path+".part", path)
+}
@@ -0,0 +1,84 @@
+package manager
+
+import (
+	"archive/zip"
+	"github.com/pkg/errors"
+	"io"
+	"launcher/manager/comp"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExtractNatives extracts the native libraries of the version required in the environment into dir,
+// skipping the entries excluded by each library
+func (v *Version) ExtractNatives(dir string, env Environment) error {
+	for _, library := range v.Libraries {
+		if !EvaluateRules(library.Rules, env) {
+			continue
+		}
+		native, ok := library.GetNativeArtifact(env)
+		if !ok {
+			continue
+		}
+		err := extractArchive(filepath.Join(comp.GetLibraryPath(), native.Path), dir, library.Extract.Exclude)
+		if err != nil {
+			return errors.WithMessage(err, "failed to extract natives of "+library.Name)
+		}
+	}
+	return nil
+}
+
+/* PRIVATE REGION */
+
+func extractArchive(archive string, dir string, exclude []string) error {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || isExcluded(f.Name, exclude) {
+			continue
+		}
+		target := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return errors.New("illegal path in archive: " + f.Name)
+		}
+		err := extractFile(f, target)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractFile(f *zip.File, target string) error {
+	err := os.MkdirAll(filepath.Dir(target), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	h, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer h.Close()
+	_, err = io.Copy(h, src)
+	return err
+}
+
+func isExcluded(name string, exclude []string) bool {
+	for _, prefix := range exclude {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
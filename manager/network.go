@@ -0,0 +1,100 @@
+package manager
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"launcher/manager/maven"
+	"launcher/network"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+var (
+	client       = http.DefaultClient
+	endpoints    = network.DefaultConfig().Endpoints
+	repositories []string
+	networkLock  sync.RWMutex
+)
+
+// Configure sets the http client and endpoints used by every request of the package
+func Configure(cfg network.Config) error {
+	c, err := network.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	networkLock.Lock()
+	defer networkLock.Unlock()
+	client = c
+	endpoints = cfg.WithDefaults().Endpoints
+	repositories = cfg.Repositories
+	return nil
+}
+
+/* PRIVATE REGION */
+
+func httpClient() *http.Client {
+	networkLock.RLock()
+	defer networkLock.RUnlock()
+	return client
+}
+
+func currentEndpoints() network.Endpoints {
+	networkLock.RLock()
+	defer networkLock.RUnlock()
+	return endpoints
+}
+
+// libraryResolver returns a maven resolver fetching checksums through fetchFile and artifacts through
+// downloadVerifiedFile, so with mirrors, retries and progress
+func libraryResolver() *maven.Resolver {
+	networkLock.RLock()
+	defer networkLock.RUnlock()
+	return &maven.Resolver{
+		Client:       client,
+		Repositories: repositories,
+		Fetch:        fetchFile,
+		Download: func(ctx context.Context, address string, path string, sum maven.Checksum) error {
+			if sum.New() == nil {
+				return errors.New("unsupported checksum algorithm " + sum.Algorithm)
+			}
+			return downloadVerifiedFile(ctx, address, path, sum.New, sum.Hash, 0)
+		},
+	}
+}
+
+func versionManifestUrl() string {
+	return currentEndpoints().Meta + "/mc/game/version_manifest_v2.json"
+}
+
+func javaRuntimesUrl() string {
+	return currentEndpoints().Meta + "/v1/products/java-runtime/2ec0cc96c44e5a76b9c8b7c39df7210883d12871/all.json"
+}
+
+func resourceUrl(hash string) string {
+	return currentEndpoints().Resources + "/" + hash[0:2] + "/" + hash
+}
+
+func fabricLoadersUrl(version string) string {
+	return currentEndpoints().Fabric + "/v2/versions/loader/" + url.PathEscape(version)
+}
+
+func fabricProfileUrl(version string, loader string) string {
+	return fabricLoadersUrl(version) + "/" + url.PathEscape(loader) + "/profile/json"
+}
+
+func quiltLoadersUrl(version string) string {
+	return currentEndpoints().Quilt + "/v3/versions/loader/" + url.PathEscape(version)
+}
+
+func forgeMetadataUrl() string {
+	return currentEndpoints().Forge + "/net/minecraftforge/forge/maven-metadata.xml"
+}
+
+func neoForgeMetadataUrl() string {
+	return currentEndpoints().NeoForge + "/net/neoforged/neoforge/maven-metadata.xml"
+}
+
+func quiltProfileUrl(version string, loader string) string {
+	return quiltLoadersUrl(version) + "/" + url.PathEscape(loader) + "/profile/json"
+}
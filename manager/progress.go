@@ -0,0 +1,173 @@
+package manager
+
+import (
+	"launcher/events"
+	"sync"
+	"time"
+)
+
+// Stages of an install reported in progress events
+const (
+	StageMetadata  = "metadata"
+	StageJava      = "java"
+	StageLoader    = "loader"
+	StageClient    = "client"
+	StageAssets    = "assets"
+	StageLibraries = "libraries"
+)
+
+const (
+	progressInterval = 100 * time.Millisecond // minimum delay between two events
+	speedWindow      = 5 * time.Second        // throughput is averaged over this window
+)
+
+// installProgress counts the bytes of the running install and reports them as progress events
+var installProgress progressTracker
+
+type progressTracker struct {
+	mu      sync.Mutex
+	active  bool
+	stage   string
+	message string
+	done    int64
+	total   int64
+	samples []progressSample
+	emitted time.Time
+}
+
+type progressSample struct {
+	at   time.Time
+	done int64
+}
+
+// progressWriter reports the bytes written through it, growing the total when their size was not known upfront
+type progressWriter struct {
+	t    *progressTracker
+	grow bool
+}
+
+/* PRIVATE REGION */
+
+// begin starts tracking an install expected to process total bytes
+func (t *progressTracker) begin(total int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.active = true
+	t.stage = ""
+	t.message = ""
+	t.done = 0
+	t.total = total
+	t.samples = nil
+	t.emitted = time.Time{}
+}
+
+// complete reports the install as done
+func (t *progressTracker) complete() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.active {
+		return
+	}
+	t.done = t.total
+	t.message = "Done"
+	t.emitLocked(true)
+}
+
+// end stops tracking, later downloads are not reported
+func (t *progressTracker) end() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.active = false
+}
+
+func (t *progressTracker) setStage(stage string, message string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stage = stage
+	t.message = message
+	t.emitLocked(true)
+}
+
+func (t *progressTracker) setMessage(message string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.message = message
+	t.emitLocked(false)
+}
+
+// expect grows the total by bytes discovered after the install began
+func (t *progressTracker) expect(bytes int64) {
+	t.count(bytes, 0)
+}
+
+// add marks bytes as done, either downloaded or found valid on disk
+func (t *progressTracker) add(bytes int64) {
+	t.count(0, bytes)
+}
+
+func (t *progressTracker) writer(grow bool) progressWriter {
+	return progressWriter{t, grow}
+}
+
+func (w progressWriter) Write(p []byte) (int, error) {
+	w.advance(int64(len(p)))
+	return len(p), nil
+}
+
+// advance reports bytes processed outside of Write, like the resumed part of a download
+func (w progressWriter) advance(n int64) {
+	if w.grow {
+		w.t.count(n, n)
+	} else {
+		w.t.count(0, n)
+	}
+}
+
+func (t *progressTracker) count(total int64, done int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.active {
+		return
+	}
+	t.total += total
+	t.done += done
+	t.emitLocked(false)
+}
+
+// emitLocked triggers a progress event, unless one was triggered less than progressInterval ago and force is unset
+func (t *progressTracker) emitLocked(force bool) {
+	now := time.Now()
+	if !t.active || (!force && now.Sub(t.emitted) < progressInterval) {
+		return
+	}
+	t.emitted = now
+
+	t.samples = append(t.samples, progressSample{now, t.done})
+	for len(t.samples) > 1 && now.Sub(t.samples[0].at) > speedWindow {
+		t.samples = t.samples[1:]
+	}
+
+	// declared sizes may be wrong, so done may overshoot the total
+	done := t.done
+	if done > t.total {
+		done = t.total
+	}
+	payload := events.ProgressUpdateEventPayload{
+		Message:    t.message,
+		Stage:      t.stage,
+		BytesDone:  done,
+		BytesTotal: t.total,
+		ETA:        -1,
+	}
+	if t.total > 0 {
+		payload.Progress = 100 * float64(done) / float64(t.total)
+	}
+	oldest := t.samples[0]
+	if elapsed := now.Sub(oldest.at).Seconds(); elapsed > 0 {
+		payload.Speed = float64(t.done-oldest.done) / elapsed
+	}
+	if payload.Speed > 0 {
+		payload.ETA = float64(t.total-done) / payload.Speed
+	}
+	events.ProgressUpdateEvent.Trigger(payload)
+}
@@ -0,0 +1,72 @@
+package manager
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// QuiltLoaderVersion is a quilt loader build available for a minecraft version
+type QuiltLoaderVersion struct {
+	Loader struct {
+		Version string `json:"version"`
+		Maven   string `json:"maven"`
+	} `json:"loader"`
+	Hashed struct {
+		Version string `json:"version"`
+		Maven   string `json:"maven"`
+	} `json:"hashed"`
+	Intermediary struct {
+		Version string `json:"version"`
+		Maven   string `json:"maven"`
+	} `json:"intermediary"`
+}
+
+// IsStable reports whether the build is a release, quilt marks betas and pre-releases with a suffix
+func (q *QuiltLoaderVersion) IsStable() bool {
+	return !strings.Contains(q.Loader.Version, "-")
+}
+
+// GetQuiltLoaders returns the quilt loader builds available for the minecraft version, newest first
+func GetQuiltLoaders(ctx context.Context, version string) ([]QuiltLoaderVersion, error) {
+	var loaders []QuiltLoaderVersion
+	err := receiveJSONObject(ctx, quiltLoadersUrl(version), &loaders)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to fetch quilt loader versions")
+	}
+	if len(loaders) == 0 {
+		return nil, errors.Errorf("quilt does not support minecraft %s", version)
+	}
+	return loaders, nil
+}
+
+/* PRIVATE REGION */
+
+// installQuilt writes the profile of the quilt loader for the minecraft version and returns its id.
+// An empty loader selects the newest stable build. The libraries of the profile are installed with the others.
+func installQuilt(ctx context.Context, version string, loader string) (string, error) {
+	if loader == "" {
+		loaders, err := GetQuiltLoaders(ctx, version)
+		if err != nil {
+			return "", err
+		}
+		loader = loaders[0].Loader.Version
+		for _, l := range loaders {
+			if l.IsStable() {
+				loader = l.Loader.Version
+				break
+			}
+		}
+	}
+
+	address := quiltProfileUrl(version, loader)
+	b, err := fetchMetadata(ctx, address, "")
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to fetch quilt profile")
+	}
+	id, err := writeLoaderProfile(b, version)
+	if err != nil {
+		removeCache(address)
+	}
+	return id, err
+}
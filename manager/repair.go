@@ -0,0 +1,189 @@
+package manager
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"launcher/manager/comp"
+	"os"
+	"path/filepath"
+)
+
+// VerifyReport lists the missing or corrupt files of a profile
+type VerifyReport struct {
+	Client     bool     `json:"client"`
+	AssetIndex bool     `json:"asset_index"`
+	LogConfig  bool     `json:"log_config"`
+	Assets     []string `json:"assets"`
+	Libraries  []string `json:"libraries"`
+}
+
+// IsValid reports whether every file was found intact
+func (r VerifyReport) IsValid() bool {
+	return !r.Client && !r.AssetIndex && !r.LogConfig && len(r.Assets) == 0 && len(r.Libraries) == 0
+}
+
+// Verify checks the client jar, asset index, log configuration, assets and libraries of the profile
+func (a *LauncherProfile) Verify() VerifyReport {
+	file := a.Version.Logging.Client.File
+	return VerifyReport{
+		Client:     !checkFile(a.JAR, a.Version.Downloads["client"].SHA1),
+		AssetIndex: a.Version.AssetIndex.ID != "" && !checkFile(getAssetIndexPath(a.Version), a.Version.AssetIndex.SHA1),
+		LogConfig:  file.ID != "" && !checkFile(a.LogCfg, file.SHA1),
+		Assets:     a.VerifyAssets(),
+		Libraries:  a.VerifyLibraries(),
+	}
+}
+
+// Repair verifies the profile and downloads again only the files found broken, returning what was broken
+func (a *LauncherProfile) Repair(ctx context.Context) (VerifyReport, error) {
+	report := a.Verify()
+	if report.IsValid() {
+		return report, nil
+	}
+
+	env := CurrentEnvironment()
+	broken := make(map[string]bool)
+	for _, name := range report.Libraries {
+		broken[name] = true
+	}
+	var libraries []Library
+	for _, lib := range a.libraries {
+		if broken[lib.Name] {
+			libraries = append(libraries, lib)
+		}
+	}
+	assets := make(map[string]Asset)
+	for _, name := range report.Assets {
+		asset := a.assets.Objects[name]
+		assets[asset.Hash] = asset // several names may share one object
+	}
+
+	installProgress.begin(report.size(a.Version, assets, libraries, env))
+	defer installProgress.end()
+
+	if report.Client {
+		installProgress.setStage(StageClient, "Repairing Minecraft")
+		err := installMinecraft(ctx, a.JAR, a.Version)
+		if err != nil {
+			return report, errors.WithMessage(err, "failed to download minecraft client")
+		}
+	}
+	if report.LogConfig {
+		err := downloadLoggingLib(ctx, a.Version)
+		if err != nil {
+			return report, errors.WithMessage(err, "failed to download logging configuration")
+		}
+	}
+	if report.AssetIndex {
+		err := writeAssetIndex(ctx, a.Version)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	if len(assets) > 0 {
+		installProgress.setStage(StageAssets, "Repairing assets")
+		sched := newScheduler()
+		for hash, asset := range assets {
+			asset := asset
+			sched.add(hash, resourceUrl(hash), func() (resourceStatus, error) {
+				return downloadAsset(ctx, asset)
+			})
+		}
+		err := sched.run(ctx)
+		if err != nil {
+			return report, errors.WithMessage(err, "failed to download assets")
+		}
+		err = a.assets.Materialize(a.Version.AssetIndex.ID, comp.GetLauncherRoot())
+		if err != nil {
+			return report, errors.WithMessage(err, "failed to copy assets into their legacy layout")
+		}
+	}
+
+	if len(libraries) > 0 && a.Loader != "" {
+		for _, lib := range libraries {
+			if lib.HasArtifact() && lib.GetArtifact().Url == "" {
+				err := a.reinstallLoader(ctx)
+				if err != nil {
+					return report, err
+				}
+				break
+			}
+		}
+	}
+	if len(libraries) > 0 {
+		installProgress.setStage(StageLibraries, "Repairing libraries")
+		sched := newScheduler()
+		for _, lib := range libraries {
+			lib := lib
+			sched.add(lib.Name, lib.GetArtifact().Url, func() (resourceStatus, error) {
+				return downloadLibrary(ctx, lib, env)
+			})
+		}
+		err := sched.run(ctx)
+		if err != nil {
+			return report, errors.WithMessage(err, "failed to download libraries")
+		}
+	}
+
+	installProgress.complete()
+	return report, nil
+}
+
+/* PRIVATE REGION */
+
+// reinstallLoader runs the installer of the loader again, which generates the libraries no repository serves
+func (a *LauncherProfile) reinstallLoader(ctx context.Context) error {
+	installProgress.setStage(StageLoader, "Repairing "+a.Loader)
+	vanilla, err := LoadVersion(a.Version.GetMinecraftVersion())
+	if err != nil {
+		return errors.WithMessage(err, "failed to load version data")
+	}
+	_, err = installLoader(ctx, a.Loader, vanilla, a.LoaderVersion)
+	if err != nil {
+		return errors.WithMessage(err, "failed to install "+a.Loader)
+	}
+	return nil
+}
+
+// size returns the bytes downloaded or verified again by a repair
+func (r VerifyReport) size(ver Version, assets map[string]Asset, libraries []Library, env Environment) int64 {
+	var size int64
+	if r.Client {
+		size += ver.Downloads["client"].Size
+	}
+	if r.LogConfig {
+		size += ver.Logging.Client.File.Size
+	}
+	for _, asset := range assets {
+		size += asset.Size
+	}
+	for _, lib := range libraries {
+		for _, artifact := range lib.GetArtifacts(env) {
+			size += artifact.Size
+		}
+	}
+	return size
+}
+
+// getAssetIndexPath returns the path the game reads the asset index of the version from
+func getAssetIndexPath(ver Version) string {
+	return filepath.Join(comp.GetIndexesPath(), ver.AssetIndex.ID+".json")
+}
+
+// writeAssetIndex stores the verified asset index of the version where the game reads it from
+func writeAssetIndex(ctx context.Context, ver Version) error {
+	err := os.MkdirAll(comp.GetIndexesPath(), os.ModePerm)
+	if err != nil {
+		return errors.WithMessage(err, "failed to create asset index directory")
+	}
+	index, err := fetchMetadata(ctx, ver.AssetIndex.Url, ver.AssetIndex.SHA1)
+	if err != nil {
+		return errors.WithMessage(err, "failed to download asset index")
+	}
+	err = os.WriteFile(getAssetIndexPath(ver), index, 0644)
+	if err != nil {
+		return errors.WithMessage(err, "failed to write asset index")
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxAttempts = 4 // attempts per url before failing over to the next mirror
+	baseBackoff = 500 * time.Millisecond
+	maxBackoff  = 8 * time.Second
+)
+
+// MirrorSettings lists mirror base urls per kind of host, tried in order before the official host.
+// A BMCLAPI style mirror would use https://bmclapi2.bangbang93.com for meta,
+// https://bmclapi2.bangbang93.com/assets for resources and https://bmclapi2.bangbang93.com/maven for maven.
+type MirrorSettings struct {
+	Meta      []string `json:"meta"`      // version manifests, version JSONs, client jars and runtimes
+	Resources []string `json:"resources"` // asset objects
+	Maven     []string `json:"maven"`     // library repositories
+}
+
+// mirroredHosts assigns the official hosts to the kind of mirror replacing them
+var mirroredHosts = map[string]func(m MirrorSettings) []string{
+	"piston-meta.mojang.com":           func(m MirrorSettings) []string { return m.Meta },
+	"piston-data.mojang.com":           func(m MirrorSettings) []string { return m.Meta },
+	"launchermeta.mojang.com":          func(m MirrorSettings) []string { return m.Meta },
+	"launcher.mojang.com":              func(m MirrorSettings) []string { return m.Meta },
+	"resources.download.minecraft.net": func(m MirrorSettings) []string { return m.Resources },
+	"libraries.minecraft.net":          func(m MirrorSettings) []string { return m.Maven },
+	"maven.fabricmc.net":               func(m MirrorSettings) []string { return m.Maven },
+	"maven.quiltmc.org":                func(m MirrorSettings) []string { return m.Maven },
+	"maven.minecraftforge.net":         func(m MirrorSettings) []string { return m.Maven },
+	"maven.neoforged.net":              func(m MirrorSettings) []string { return m.Maven },
+}
+
+var (
+	mirrors     MirrorSettings
+	mirrorsLock sync.RWMutex
+)
+
+// SetMirrors configures the mirrors used by every download
+func SetMirrors(m MirrorSettings) {
+	mirrorsLock.Lock()
+	defer mirrorsLock.Unlock()
+	mirrors = m
+}
+
+// statusError is returned for responses with an unexpected status code
+type statusError struct {
+	status     int
+	address    string
+	retryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d %s while fetching %s", e.status, http.StatusText(e.status), e.address)
+}
+
+/* PRIVATE REGION */
+
+func newStatusError(r *http.Response, address string) error {
+	e := &statusError{status: r.StatusCode, address: address}
+	if seconds, err := strconv.Atoi(r.Header.Get("Retry-After")); err == nil {
+		e.retryAfter = time.Duration(seconds) * time.Second
+	}
+	return e
+}
+
+// withRetry calls fetch with the mirrors of address and finally address itself. Transient failures are retried
+// with exponential backoff, any other failure moves on to the next url. The error of the last attempt is returned,
+// or the error of ctx once it is done.
+func withRetry(ctx context.Context, address string, fetch func(address string) error) error {
+	var err error
+	for _, candidate := range candidateUrls(address) {
+		for attempt := 0; attempt < maxAttempts; attempt++ {
+			if attempt > 0 {
+				select {
+				case <-time.After(backoff(attempt, err)):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			err = fetch(candidate)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err == nil || !isTransient(err) {
+				break
+			}
+		}
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// candidateUrls returns address rewritten onto every configured mirror of its host, followed by address itself
+func candidateUrls(address string) []string {
+	u, err := url.Parse(address)
+	if err != nil {
+		return []string{address}
+	}
+	selector, ok := mirroredHosts[u.Host]
+	if !ok {
+		return []string{address}
+	}
+
+	mirrorsLock.RLock()
+	bases := selector(mirrors)
+	mirrorsLock.RUnlock()
+
+	var ret []string
+	for _, base := range bases {
+		ret = append(ret, strings.TrimSuffix(base, "/")+u.RequestURI())
+	}
+	return append(ret, address)
+}
+
+// isTransient reports whether retrying the request that failed with err may succeed
+func isTransient(err error) bool {
+	var status *statusError
+	if errors.As(err, &status) {
+		// a rejected range means the part file was discarded, so the next attempt starts over
+		return status.status == http.StatusTooManyRequests || status.status == http.StatusRequestedRangeNotSatisfiable || status.status >= 500
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	// an unknown host or a rejected certificate fail again, a refused or reset connection may not
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && (opErr.Op == "dial" || opErr.Op == "read" || opErr.Op == "write") {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// backoff returns the delay before the given attempt, honoring the Retry-After of the previous response
+func backoff(attempt int, err error) time.Duration {
+	var status *statusError
+	if errors.As(err, &status) && status.retryAfter > 0 && status.retryAfter <= maxBackoff {
+		return status.retryAfter
+	}
+	delay := baseBackoff << uint(attempt-1)
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1)) // jitter over the upper half
+}
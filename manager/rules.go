@@ -0,0 +1,160 @@
+package manager
+
+import (
+	"encoding/json"
+	"launcher/manager/comp"
+	"regexp"
+	"runtime"
+)
+
+// Feature flags referenced by argument rules
+const (
+	FeatureDemoUser            = "is_demo_user"
+	FeatureCustomResolution    = "has_custom_resolution"
+	FeatureQuickPlaysSupport   = "has_quick_plays_support"
+	FeatureQuickPlaySinglePlay = "is_quick_play_singleplayer"
+	FeatureQuickPlayMultiplay  = "is_quick_play_multiplayer"
+	FeatureQuickPlayRealms     = "is_quick_play_realms"
+)
+
+// Environment describes the platform rules are evaluated against, using Mojang's naming
+type Environment struct {
+	OS        string          // windows, osx or linux
+	Arch      string          // x86, x86_64, arm32 or arm64
+	OSVersion string          // matched against the os.version regex of rules
+	Features  map[string]bool // enabled launcher features
+}
+
+type Rule struct {
+	Action string `json:"action"`
+	OS     struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+		Arch    string `json:"arch"`
+	} `json:"os"`
+	Features map[string]bool `json:"features"`
+}
+
+// Argument is a launch argument, which is either a plain string or a list of values guarded by rules
+type Argument struct {
+	Value []string
+	Rules []Rule
+}
+
+// CurrentEnvironment returns the environment of the running system, with no features enabled
+func CurrentEnvironment() Environment {
+	return Environment{
+		OS:        mojangOS(runtime.GOOS),
+		Arch:      mojangArch(runtime.GOARCH),
+		OSVersion: comp.GetOSVersion(),
+		Features:  map[string]bool{},
+	}
+}
+
+// WithFeature returns a copy of the environment with the feature set to the given value
+func (e Environment) WithFeature(feature string, value bool) Environment {
+	features := make(map[string]bool, len(e.Features)+1)
+	for k, v := range e.Features {
+		features[k] = v
+	}
+	features[feature] = value
+	e.Features = features
+	return e
+}
+
+// EvaluateRules reports whether a library or argument guarded by the rules is allowed in the environment.
+// No rules allow everything, otherwise the last matching rule decides and nothing is allowed when none matches.
+func EvaluateRules(rules []Rule, env Environment) bool {
+	if len(rules) == 0 {
+		return true
+	}
+	allowed := false
+	for i := range rules {
+		if rules[i].Matches(env) {
+			allowed = rules[i].Action != "disallow"
+		}
+	}
+	return allowed
+}
+
+// Matches reports whether all conditions of the rule hold in the environment
+func (r *Rule) Matches(env Environment) bool {
+	if r.OS.Name != "" && r.OS.Name != env.OS {
+		return false
+	}
+	if r.OS.Arch != "" && r.OS.Arch != env.Arch {
+		return false
+	}
+	if r.OS.Version != "" {
+		matched, err := regexp.MatchString(r.OS.Version, env.OSVersion)
+		if err != nil || !matched {
+			return false
+		}
+	}
+	for feature, value := range r.Features {
+		if env.Features[feature] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Allowed reports whether the argument applies in the environment
+func (a *Argument) Allowed(env Environment) bool {
+	return EvaluateRules(a.Rules, env)
+}
+
+func (a *Argument) UnmarshalJSON(b []byte) error {
+	var str string
+	if err := json.Unmarshal(b, &str); err == nil {
+		a.Value = []string{str}
+		a.Rules = nil
+		return nil
+	}
+
+	var obj struct {
+		Rules []Rule          `json:"rules"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return err
+	}
+	a.Rules = obj.Rules
+	if err := json.Unmarshal(obj.Value, &str); err == nil {
+		a.Value = []string{str}
+		return nil
+	}
+	return json.Unmarshal(obj.Value, &a.Value)
+}
+
+func (a Argument) MarshalJSON() ([]byte, error) {
+	if len(a.Rules) == 0 && len(a.Value) == 1 {
+		return json.Marshal(a.Value[0])
+	}
+	return json.Marshal(struct {
+		Rules []Rule   `json:"rules"`
+		Value []string `json:"value"`
+	}{a.Rules, a.Value})
+}
+
+/* PRIVATE REGION */
+
+func mojangOS(goos string) string {
+	if goos == "darwin" {
+		return "osx"
+	}
+	return goos
+}
+
+func mojangArch(goarch string) string {
+	switch goarch {
+	case "386":
+		return "x86"
+	case "amd64":
+		return "x86_64"
+	case "arm":
+		return "arm32"
+	default:
+		return goarch
+	}
+}
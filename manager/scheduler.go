@@ -0,0 +1,135 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"net/url"
+	"sync"
+)
+
+const (
+	defaultWorkers = 16 // concurrent downloads overall
+	defaultPerHost = 8  // concurrent downloads per host
+)
+
+// scheduler runs download jobs on a bounded worker pool, limiting the concurrent requests made to each host
+type scheduler struct {
+	workers    int
+	perHost    int
+	onProgress func(done int, total int) // called after every finished job, never concurrently
+
+	jobs  []schedulerJob
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+	done  int
+}
+
+type schedulerJob struct {
+	name string
+	host string
+	run  func() (resourceStatus, error)
+}
+
+// schedulerError aggregates the failed jobs of a run
+type schedulerError struct {
+	failures map[string]error
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		workers: defaultWorkers,
+		perHost: defaultPerHost,
+		hosts:   make(map[string]chan struct{}),
+	}
+}
+
+// add queues a job downloading from address, name identifies it in errors
+func (s *scheduler) add(name string, address string, run func() (resourceStatus, error)) {
+	host := ""
+	if u, err := url.Parse(address); err == nil {
+		host = u.Host
+	}
+	s.jobs = append(s.jobs, schedulerJob{name: name, host: host, run: run})
+}
+
+// run executes every queued job and waits for them, returning a schedulerError when any failed.
+// Once ctx is done, the jobs not started yet are dropped and the error of ctx is returned.
+func (s *scheduler) run(ctx context.Context) error {
+	queue := make(chan schedulerJob)
+	failures := make(map[string]error)
+	var wg sync.WaitGroup
+
+	workers := s.workers
+	if workers > len(s.jobs) {
+		workers = len(s.jobs)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				res, err := s.execute(job)
+				s.mu.Lock()
+				if res == Failed {
+					if err == nil {
+						err = errors.New("unknown error")
+					}
+					failures[job.name] = err
+				}
+				s.done++
+				if s.onProgress != nil {
+					s.onProgress(s.done, len(s.jobs))
+				}
+				s.mu.Unlock()
+			}
+		}()
+	}
+dispatch:
+	for _, job := range s.jobs {
+		select {
+		case queue <- job:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if len(failures) > 0 {
+		return &schedulerError{failures}
+	}
+	return nil
+}
+
+func (s *scheduler) execute(job schedulerJob) (resourceStatus, error) {
+	slot := s.hostSlot(job.host)
+	slot <- struct{}{}
+	defer func() { <-slot }()
+	return job.run()
+}
+
+func (s *scheduler) hostSlot(host string) chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	slot, ok := s.hosts[host]
+	if !ok {
+		slot = make(chan struct{}, s.perHost)
+		s.hosts[host] = slot
+	}
+	return slot
+}
+
+func (e *schedulerError) Error() string {
+	for name, err := range e.failures {
+		if len(e.failures) == 1 {
+			return fmt.Sprintf("failed to download %s: %s", name, err.Error())
+		}
+		return fmt.Sprintf("failed to download %d files, including %s: %s", len(e.failures), name, err.Error())
+	}
+	return "no failures"
+}
@@ -0,0 +1,202 @@
+package manager
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"io/fs"
+	"launcher/manager/comp"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// StoreIndex records which installed versions reference each file of the shared store
+type StoreIndex struct {
+	Files map[string][]string `json:"files"` // slash separated path relative to the launcher root, to version ids
+}
+
+// GCReport lists the orphaned files a garbage collection removed, or would remove on a dry run
+type GCReport struct {
+	DryRun bool     `json:"dry_run"`
+	Files  []string `json:"files"` // slash separated paths relative to the launcher root
+	Bytes  int64    `json:"bytes"` // space reclaimed
+}
+
+// gcRoots are the directories garbage collection removes unreferenced files from
+var gcRoots = []func() string{comp.GetLibraryPath, comp.GetObjectsPath, comp.GetLogCfgsPath, comp.GetIndexesPath}
+
+// ReadStoreIndex returns the store index written by the last install or garbage collection
+func ReadStoreIndex() (StoreIndex, error) {
+	var s StoreIndex
+	b, err := os.ReadFile(comp.GetStoreIndexPath())
+	if err != nil {
+		return StoreIndex{}, err
+	}
+	err = json.Unmarshal(b, &s)
+	if err != nil {
+		return StoreIndex{}, err
+	}
+	return s, nil
+}
+
+// UpdateStoreIndex rebuilds the store index from the installed versions and writes it
+func UpdateStoreIndex() (StoreIndex, error) {
+	s := StoreIndex{Files: make(map[string][]string)}
+	dir, err := os.ReadDir(filepath.Join(comp.GetLauncherRoot(), "versions"))
+	if err != nil && !os.IsNotExist(err) {
+		return StoreIndex{}, err
+	}
+	env := CurrentEnvironment()
+	for _, entry := range dir {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(GetVersionFilePath(entry.Name())); err != nil {
+			continue // not a version directory
+		}
+		ver, err := LoadVersion(entry.Name())
+		if err != nil {
+			return StoreIndex{}, errors.WithMessage(err, "failed to load version "+entry.Name())
+		}
+		files, err := referencedFiles(ver, env)
+		if err != nil {
+			return StoreIndex{}, errors.WithMessage(err, "failed to list the files of version "+entry.Name())
+		}
+		for _, file := range files {
+			s.Files[file] = append(s.Files[file], ver.ID)
+		}
+	}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		return StoreIndex{}, err
+	}
+	err = os.WriteFile(comp.GetStoreIndexPath(), b, 0644)
+	if err != nil {
+		return StoreIndex{}, errors.WithMessage(err, "failed to write store index")
+	}
+	return s, nil
+}
+
+// References returns the ids of the versions referencing the file at path
+func (s *StoreIndex) References(path string) []string {
+	rel, err := filepath.Rel(comp.GetLauncherRoot(), path)
+	if err != nil {
+		return nil
+	}
+	return s.Files[filepath.ToSlash(rel)]
+}
+
+// CollectGarbage removes the libraries, assets, log configurations and asset indexes no installed version
+// references. On a dry run, the files are only reported. Partial downloads are kept so that they can resume.
+func CollectGarbage(dryRun bool) (GCReport, error) {
+	s, err := UpdateStoreIndex()
+	if err != nil {
+		return GCReport{}, errors.WithMessage(err, "failed to index the store, nothing was removed")
+	}
+
+	report := GCReport{DryRun: dryRun}
+	for _, root := range gcRoots {
+		err := filepath.WalkDir(root(), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() || strings.HasSuffix(path, partSuffix) || len(s.References(path)) > 0 {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(comp.GetLauncherRoot(), path)
+			report.Files = append(report.Files, filepath.ToSlash(rel))
+			report.Bytes += info.Size()
+			if dryRun {
+				return nil
+			}
+			return os.Remove(path)
+		})
+		if err != nil {
+			return report, err
+		}
+		if !dryRun {
+			removeEmptyDirs(root())
+		}
+	}
+	sort.Strings(report.Files)
+	return report, nil
+}
+
+/* PRIVATE REGION */
+
+// referencedFiles returns the slash separated paths, relative to the launcher root, of the files the version uses.
+// Assets are read from the installed asset index, so that collecting garbage never depends on the network.
+func referencedFiles(ver Version, env Environment) ([]string, error) {
+	var paths []string
+	add := func(path string) {
+		rel, err := filepath.Rel(comp.GetLauncherRoot(), path)
+		if err == nil {
+			paths = append(paths, filepath.ToSlash(rel))
+		}
+	}
+
+	add(GetVersionJARPath(ver.Jar))
+	if ver.Logging.Client.File.ID != "" {
+		add(filepath.Join(comp.GetLogCfgsPath(), ver.Logging.Client.File.ID))
+	}
+	for _, lib := range ver.Libraries {
+		if !EvaluateRules(lib.Rules, env) {
+			continue
+		}
+		for _, artifact := range lib.GetArtifacts(env) {
+			add(filepath.Join(comp.GetLibraryPath(), artifact.Path))
+		}
+	}
+	// forge installers produce files, like the deobfuscated client, that only their launch arguments name
+	outputs, err := readInstallerOutputs(ver.ID)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	paths = append(paths, outputs...)
+
+	if ver.AssetIndex.ID == "" {
+		return paths, nil
+	}
+	index := getAssetIndexPath(ver)
+	add(index)
+	b, err := os.ReadFile(index)
+	if os.IsNotExist(err) {
+		return paths, nil // the assets were never installed
+	}
+	if err != nil {
+		return nil, err
+	}
+	var assets AssetIndex
+	err = json.Unmarshal(b, &assets)
+	if err != nil {
+		return nil, errors.WithMessage(err, "corrupt asset index "+index)
+	}
+	for _, asset := range assets.Objects {
+		add(asset.GetObjectPath())
+	}
+	return paths, nil
+}
+
+// removeEmptyDirs removes the empty directories below root
+func removeEmptyDirs(root string) {
+	var dirs []string
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() && path != root {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	// deepest first, so that parents emptied by their children are removed too
+	for i := len(dirs) - 1; i >= 0; i-- {
+		_ = os.Remove(dirs[i]) // fails unless empty
+	}
+}
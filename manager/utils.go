@@ -1,7 +1,9 @@
 package manager
 
 import (
+	"launcher/manager/maven"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -18,23 +20,13 @@ func findJAR(dir string) string {
 	return ""
 }
 
-type ProgressBar struct {
-	max     int
-	current float64
-	piece   float64
-}
-
-func (p *ProgressBar) SetTaskAmount(amount int) {
-	p.max = amount
-	p.piece = float64(100) / float64(amount)
-}
-
-func (p *ProgressBar) TaskFinished() {
-	if p.current+p.piece > float64(p.max) {
-		p.current = float64(p.max)
-	} else {
-		p.current += p.piece
+// mavenPath converts a maven coordinate into its repository path, empty for invalid coordinates
+func mavenPath(name string) string {
+	c, err := maven.Parse(name)
+	if err != nil {
+		return ""
 	}
+	return filepath.FromSlash(c.Path())
 }
 
 type Precision uint8
@@ -47,10 +39,10 @@ var (
 )
 
 func CompareVersion(required string, current string, precision Precision) bool {
-	regex, _ := regexp.Compile("^\\d*.\\d*.\\d*$")
+	regex, _ := regexp.Compile("^\\d+(\\.\\d+){0,3}$") // 17, 17.0.2 and 17.0.2.1 are all valid
 	if regex.MatchString(required) && regex.MatchString(current) {
 		var toParts = func(s string) (int, int, int) {
-			parts := strings.Split(s, ".")
+			parts := append(strings.Split(s, "."), "0", "0")
 			major, _ := strconv.Atoi(parts[0])
 			minor, _ := strconv.Atoi(parts[1])
 			patch, _ := strconv.Atoi(parts[2])
@@ -1,5 +1,147 @@
 package manager
 
-var (
-	GlobalMinecraftVersion = "1.19"
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"launcher/manager/comp"
+	"os"
+	"path/filepath"
+	"strings"
 )
+
+// maxInheritanceDepth guards against cyclic inheritsFrom chains
+const maxInheritanceDepth = 8
+
+// LoadVersion loads the version JSON of the given profile id and resolves its inheritsFrom chain,
+// merging every child over its parent. Vanilla versions missing on disk are taken from the manifest.
+func LoadVersion(id string) (Version, error) {
+	return loadVersion(id, 0)
+}
+
+// GetVersionFilePath returns the path of the version JSON of the given profile id
+func GetVersionFilePath(id string) string {
+	return filepath.Join(comp.GetLauncherRoot(), "versions", id, id+".json")
+}
+
+// GetVersionJARPath returns the path of the client jar of the given profile id
+func GetVersionJARPath(id string) string {
+	return filepath.Join(comp.GetLauncherRoot(), "versions", id, id+".jar")
+}
+
+/* PRIVATE REGION */
+
+func loadVersion(id string, depth int) (Version, error) {
+	if depth > maxInheritanceDepth {
+		return Version{}, errors.Errorf("inheritance chain of version \"%s\" is too deep", id)
+	}
+
+	ver, err := readVersionFile(GetVersionFilePath(id))
+	if os.IsNotExist(err) {
+		mf, err := GetManifest()
+		if err != nil {
+			return Version{}, errors.WithMessage(err, "failed to fetch manifest")
+		}
+		ver, err = mf.GetVersion(id)
+		if err != nil {
+			return Version{}, err
+		}
+	} else if err != nil {
+		return Version{}, errors.WithMessage(err, "failed to read version file of "+id)
+	}
+
+	if ver.ID == "" {
+		ver.ID = id
+	}
+	if ver.InheritsFrom == "" {
+		if ver.Jar == "" {
+			ver.Jar = ver.ID
+		}
+		ver.baseVersion = ver.ID
+		return ver, nil
+	}
+
+	parent, err := loadVersion(ver.InheritsFrom, depth+1)
+	if err != nil {
+		return Version{}, errors.WithMessage(err, "failed to load parent version of "+id)
+	}
+	return ver.mergeOver(parent), nil
+}
+
+// mergeOver merges the child version v over its parent, the child taking precedence
+func (v Version) mergeOver(parent Version) Version {
+	ret := parent
+	ret.ID = v.ID
+	ret.InheritsFrom = ""
+	if v.Type != "" {
+		ret.Type = v.Type
+	}
+	if v.MainClass != "" {
+		ret.MainClass = v.MainClass
+	}
+	if v.JavaVersion.Component != "" {
+		ret.JavaVersion = v.JavaVersion
+	}
+	if v.MinecraftArguments != "" {
+		ret.MinecraftArguments = v.MinecraftArguments
+	}
+	if v.Jar != "" {
+		ret.Jar = v.Jar
+	}
+	if v.AssetIndex.ID != "" {
+		ret.AssetIndex = v.AssetIndex
+		ret.Assets = v.Assets
+	}
+	if v.Logging.Client.File.ID != "" {
+		ret.Logging = v.Logging
+	}
+	if len(v.Downloads) > 0 {
+		downloads := make(map[string]Artifact)
+		for key, download := range parent.Downloads {
+			downloads[key] = download
+		}
+		for key, download := range v.Downloads {
+			downloads[key] = download
+		}
+		ret.Downloads = downloads
+	}
+
+	ret.Arguments.JVM = append(append([]Argument{}, parent.Arguments.JVM...), v.Arguments.JVM...)
+	ret.Arguments.Game = append(append([]Argument{}, parent.Arguments.Game...), v.Arguments.Game...)
+
+	overridden := make(map[string]bool)
+	var libraries []Library
+	for _, library := range v.Libraries {
+		overridden[libraryKey(library.Name)] = true
+		libraries = append(libraries, library)
+	}
+	for _, library := range parent.Libraries {
+		if !overridden[libraryKey(library.Name)] {
+			libraries = append(libraries, library)
+		}
+	}
+	ret.Libraries = libraries
+	return ret
+}
+
+// libraryKey strips the version from a maven name, so that a child can replace a parent's library
+func libraryKey(name string) string {
+	seg := strings.Split(name, ":")
+	if len(seg) < 3 {
+		return name
+	}
+	return strings.Join(append(seg[:2:2], seg[3:]...), ":")
+}
+
+// readVersionFile parses the version JSON stored in a profile directory
+func readVersionFile(path string) (Version, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Version{}, err
+	}
+	var ver Version
+	err = json.Unmarshal(b, &ver)
+	if err != nil {
+		return Version{}, err
+	}
+	return ver, nil
+}
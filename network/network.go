@@ -0,0 +1,163 @@
+package network
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/pkg/errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout   = 30 // seconds
+	defaultUserAgent = "GenecraftLauncher/1.0.0"
+)
+
+// Config configures the http client and the endpoints used by the api and manager packages
+type Config struct {
+	Endpoints Endpoints `json:"endpoints"`
+	Timeout   int       `json:"timeout"`    // seconds to connect and receive the response headers, 0 uses the default
+	UserAgent string    `json:"user_agent"` // empty uses the default
+	CABundle  string    `json:"ca_bundle"`  // PEM file trusted in addition to the system roots
+	Proxy     string    `json:"proxy"`      // http, https or socks5 url, empty uses the environment
+	// Repositories lists maven repositories tried for a library after the repository it declares
+	Repositories []string `json:"repositories"`
+}
+
+// Endpoints holds the base urls of the remote services, without a trailing slash
+type Endpoints struct {
+	Meta      string `json:"meta"`      // version manifest and java runtimes
+	Resources string `json:"resources"` // asset objects
+	Libraries string `json:"libraries"` // default library repository
+	Fabric    string `json:"fabric"`    // fabric meta service
+	Quilt     string `json:"quilt"`     // quilt meta service
+	Forge     string `json:"forge"`     // forge maven repository
+	NeoForge  string `json:"neoforge"`  // neoforge maven repository
+	Authority string `json:"authority"` // microsoft identity platform
+	XboxUser  string `json:"xbox_user"`
+	XboxXSTS  string `json:"xbox_xsts"`
+	Minecraft string `json:"minecraft"` // minecraft services
+	Backend   string `json:"backend"`   // wardrobe assets
+}
+
+// DefaultConfig returns the configuration of the production services
+func DefaultConfig() Config {
+	return Config{
+		Endpoints: Endpoints{
+			Meta:      "https://piston-meta.mojang.com",
+			Resources: "https://resources.download.minecraft.net",
+			Libraries: "https://libraries.minecraft.net",
+			Fabric:    "https://meta.fabricmc.net",
+			Quilt:     "https://meta.quiltmc.org",
+			Forge:     "https://maven.minecraftforge.net",
+			NeoForge:  "https://maven.neoforged.net/releases",
+			Authority: "https://login.microsoftonline.com/consumers",
+			XboxUser:  "https://user.auth.xboxlive.com",
+			XboxXSTS:  "https://xsts.auth.xboxlive.com",
+			Minecraft: "https://api.minecraftservices.com",
+			Backend:   "https://bc97-78-98-240-12.eu.ngrok.io",
+		},
+		Timeout:   defaultTimeout,
+		UserAgent: defaultUserAgent,
+	}
+}
+
+// WithDefaults returns c with every empty field taken from DefaultConfig and trailing slashes removed
+func (c Config) WithDefaults() Config {
+	d := DefaultConfig()
+	fill(&c.Endpoints.Meta, d.Endpoints.Meta)
+	fill(&c.Endpoints.Resources, d.Endpoints.Resources)
+	fill(&c.Endpoints.Libraries, d.Endpoints.Libraries)
+	fill(&c.Endpoints.Fabric, d.Endpoints.Fabric)
+	fill(&c.Endpoints.Quilt, d.Endpoints.Quilt)
+	fill(&c.Endpoints.Forge, d.Endpoints.Forge)
+	fill(&c.Endpoints.NeoForge, d.Endpoints.NeoForge)
+	fill(&c.Endpoints.Authority, d.Endpoints.Authority)
+	fill(&c.Endpoints.XboxUser, d.Endpoints.XboxUser)
+	fill(&c.Endpoints.XboxXSTS, d.Endpoints.XboxXSTS)
+	fill(&c.Endpoints.Minecraft, d.Endpoints.Minecraft)
+	fill(&c.Endpoints.Backend, d.Endpoints.Backend)
+	fill(&c.UserAgent, d.UserAgent)
+	if c.Timeout <= 0 {
+		c.Timeout = d.Timeout
+	}
+	return c
+}
+
+// NewClient creates a http client honoring the timeout, user agent, ca bundle and proxy of c.
+// The timeout does not limit reading the body, so large downloads are not interrupted.
+func NewClient(c Config) (*http.Client, error) {
+	c = c.WithDefaults()
+	timeout := time.Duration(c.Timeout) * time.Second
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = timeout
+	transport.ResponseHeaderTimeout = timeout
+
+	if c.Proxy != "" {
+		proxy, err := url.Parse(c.Proxy)
+		if err != nil {
+			return nil, errors.WithMessage(err, "invalid proxy url")
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, errors.Errorf("unsupported proxy scheme %s", proxy.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if c.CABundle != "" {
+		pool, err := certPool(c.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return &http.Client{Transport: &userAgentTransport{c.UserAgent, transport}}, nil
+}
+
+/* PRIVATE REGION */
+
+// userAgentTransport sets the user agent of requests that do not specify one
+type userAgentTransport struct {
+	userAgent string
+	next      http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// certPool returns the system roots extended with the certificates of the PEM file
+func certPool(file string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to read ca bundle")
+	}
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, errors.New("no certificate found in ca bundle " + file)
+	}
+	return pool, nil
+}
+
+func fill(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+	*field = strings.TrimSuffix(*field, "/")
+}
@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/wailsapp/wails/v2/pkg/logger"
+	"launcher/logging"
+	"launcher/manager"
+	"launcher/manager/comp"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAssetLayouts(t *testing.T) {
+	logging.Logger = logger.NewDefaultLogger()
+
+	icon := []byte("icon")
+	sound := []byte("sound")
+	for version, layout := range map[string]string{
+		"1.19":   "",
+		"1.7.10": `"virtual": true,`,
+		"1.5.2":  `"map_to_resources": true,`,
+	} {
+		t.Run(version, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+
+			index := []byte(fmt.Sprintf(`{%s "objects": {
+				"icons/icon.png": {"hash": "%s", "size": 4},
+				"sounds/step.ogg": {"hash": "%s", "size": 5}
+			}}`, layout, sha1Hex(icon), sha1Hex(sound)))
+			services := newFakeServices(t)
+			services.serveVanilla(version, index)
+			services.serve("/"+sha1Hex(icon)[0:2]+"/"+sha1Hex(icon), icon)
+			services.serve("/"+sha1Hex(sound)[0:2]+"/"+sha1Hex(sound), sound)
+			services.serve("/v2/versions/loader/"+version, []byte(`[{"loader": {"version": "0.14.8", "stable": true}}]`))
+			services.serveFabricLoader(version, "0.14.8")
+
+			if err := manager.InstallProfile(context.Background(), version, manager.LoaderFabric); err != nil {
+				t.Fatal(err)
+			}
+
+			var assets manager.AssetIndex
+			if err := json.Unmarshal(index, &assets); err != nil {
+				t.Fatal(err)
+			}
+			expected := comp.GetAssetsPath()
+			switch {
+			case assets.Virtual:
+				expected = filepath.Join(comp.GetVirtualAssetsPath(), version)
+			case assets.MapToResources:
+				expected = filepath.Join(comp.GetLauncherRoot(), "resources")
+			}
+			dir := assets.GetGameAssetsPath(version, comp.GetLauncherRoot())
+			if dir != expected {
+				t.Errorf("game assets read from %s, expected %s", dir, expected)
+			}
+
+			// legacy layouts hold a copy of every object under its name, the object store keeps them by hash
+			for name, data := range map[string][]byte{"icons/icon.png": icon, "sounds/step.ogg": sound} {
+				asset := manager.Asset{Hash: sha1Hex(data)}
+				if b, err := os.ReadFile(asset.GetObjectPath()); err != nil || string(b) != string(data) {
+					t.Error("object not downloaded:", name, err)
+				}
+				_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+				if layout != "" && err != nil {
+					t.Error("asset not materialized:", name, err)
+				}
+				if layout == "" && err == nil {
+					t.Error("asset materialized for an object store index:", name)
+				}
+			}
+
+			// ${game_assets} is replaced by the directory of the layout
+			var ver manager.Version
+			if err := json.Unmarshal([]byte(`{"id": "`+version+`", "minecraftArguments": "--assetsDir ${game_assets}"}`), &ver); err != nil {
+				t.Fatal(err)
+			}
+			_, game := ver.CreateCommandLine("client.jar", manager.LaunchPlaceholders{GameAssets: dir},
+				manager.LaunchOptions{Env: manager.CurrentEnvironment()}, nil, nil)
+			if len(game) != 2 || game[0] != "--assetsDir" || game[1] != expected {
+				t.Error("unexpected game arguments", game)
+			}
+		})
+	}
+}
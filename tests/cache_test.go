@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"launcher/manager"
+	"launcher/manager/comp"
+	"launcher/network"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestCorruptCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	index := []byte(`{"objects": {"icons/icon.png": {"hash": "0000000000000000000000000000000000000000", "size": 4}}}`)
+	var conditional []bool
+	var lock sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		conditional = append(conditional, r.Header.Get("If-None-Match") != "")
+		if r.Header.Get("If-None-Match") == `"index"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"index"`)
+		_, _ = w.Write(index)
+	}))
+	defer server.Close()
+
+	url := server.URL + "/index.json"
+	writeVersion(t, "1.19", fmt.Sprintf(`{"id": "1.19", "assetIndex": {"id": "1.19", "url": "%s", "sha1": "%x"}}`, url, sha1.Sum(index)))
+	ver, err := manager.LoadVersion("1.19")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ver.GetAssets(); err != nil {
+		t.Fatal(err)
+	}
+
+	cached := filepath.Join(comp.GetCachePath(), "meta", fmt.Sprintf("%x", sha1.Sum([]byte(url)))+".json")
+	if err := os.WriteFile(cached, []byte(`{"objects": {}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	assets, err := ver.GetAssets()
+	if err != nil || len(assets.Objects) != 1 {
+		t.Fatal("corrupt cache not replaced", err)
+	}
+	if len(conditional) != 2 || conditional[1] {
+		t.Error("corrupt cache revalidated", conditional)
+	}
+	if b, err := os.ReadFile(cached); err != nil || string(b) != string(index) {
+		t.Error("cache not rewritten", err)
+	}
+}
+
+func TestTruncatedCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	manifest := []byte(`{"latest": {"release": "1.19"}, "versions": [{"id": "1.19", "type": "release"}]}`)
+	var conditional []bool
+	var lock sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		conditional = append(conditional, r.Header.Get("If-None-Match") != "")
+		if r.Header.Get("If-None-Match") == `"manifest"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"manifest"`)
+		_, _ = w.Write(manifest)
+	}))
+	defer server.Close()
+	if err := manager.Configure(network.Config{Endpoints: network.Endpoints{Meta: server.URL}}); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Configure(network.Config{})
+
+	if _, err := manager.GetManifest(); err != nil {
+		t.Fatal(err)
+	}
+	cached := filepath.Join(comp.GetCachePath(), "meta", fmt.Sprintf("%x", sha1.Sum([]byte(server.URL+"/mc/game/version_manifest_v2.json"))))
+	for _, interrupted := range []bool{false, true} {
+		// the manifest has no hash, a truncated copy would be confirmed by its ETag
+		if err := os.WriteFile(cached+".json", manifest[:20], 0644); err != nil {
+			t.Fatal(err)
+		}
+		if interrupted {
+			_ = os.Remove(cached + ".meta") // the write of the copy never finished
+		}
+		lock.Lock()
+		conditional = nil
+		lock.Unlock()
+		mf, err := manager.GetManifest()
+		if err != nil || mf.Latest.Release != "1.19" {
+			t.Fatal("truncated cache returned", err)
+		}
+		if last := conditional[len(conditional)-1]; last {
+			t.Error("truncated cache revalidated", interrupted, conditional)
+		}
+		if b, err := os.ReadFile(cached + ".json"); err != nil || string(b) != string(manifest) {
+			t.Error("cache not rewritten", err)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"github.com/pkg/errors"
+	"launcher/events"
+	"launcher/manager"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestResumedDownload(t *testing.T) {
+	content := bytes.Repeat([]byte("minecraft"), 4096)
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/client.jar" {
+			http.NotFound(w, r)
+			return
+		}
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "client.jar", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	jar := filepath.Join(t.TempDir(), "versions", "1.19", "1.19.jar")
+	_ = os.MkdirAll(filepath.Dir(jar), os.ModePerm)
+	if err := os.WriteFile(jar+".part", content[:1000], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	profile := manager.LauncherProfile{JAR: jar}
+	profile.Version.Downloads = map[string]manager.Artifact{"client": {
+		Url:  server.URL + "/client.jar",
+		SHA1: fmt.Sprintf("%x", sha1.Sum(content)),
+		Size: int64(len(content)),
+	}}
+	if err := profile.InstallMinecraft(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(jar)
+	if err != nil || !bytes.Equal(b, content) {
+		t.Fatal("downloaded file does not match")
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes=1000-" {
+		t.Error("download was not resumed", ranges)
+	}
+	if _, err := os.Stat(jar + ".part"); !os.IsNotExist(err) {
+		t.Error("part file left behind")
+	}
+
+	missing := manager.LauncherProfile{JAR: filepath.Join(filepath.Dir(jar), "missing.jar")}
+	missing.Version.Downloads = map[string]manager.Artifact{"client": {Url: server.URL + "/missing.jar", SHA1: "0"}}
+	err = missing.InstallMinecraft(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Error("error page accepted as a download", err)
+	}
+	if _, err := os.Stat(missing.JAR); !os.IsNotExist(err) {
+		t.Error("error page written to the final path")
+	}
+}
+
+func TestMirrorRetry(t *testing.T) {
+	content := []byte("client jar")
+	requests := 0
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path != "/v1/objects/abc/client.jar" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(content)
+	}))
+	defer mirror.Close()
+
+	manager.SetMirrors(manager.MirrorSettings{Meta: []string{mirror.URL}})
+	defer manager.SetMirrors(manager.MirrorSettings{})
+
+	profile := manager.LauncherProfile{JAR: filepath.Join(t.TempDir(), "client.jar")}
+	profile.Version.Downloads = map[string]manager.Artifact{"client": {
+		Url:  "https://piston-data.mojang.com/v1/objects/abc/client.jar",
+		SHA1: fmt.Sprintf("%x", sha1.Sum(content)),
+	}}
+	if err := profile.InstallMinecraft(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Error("expected the mirror to be retried once, got requests:", requests)
+	}
+}
+
+func TestPermanentNetworkError(t *testing.T) {
+	content := []byte("client jar")
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(content)
+	}))
+	defer mirror.Close()
+
+	// a mirror the client cannot speak to fails over at once instead of waiting out the backoff
+	manager.SetMirrors(manager.MirrorSettings{Meta: []string{"ftp://mirror.example", mirror.URL}})
+	defer manager.SetMirrors(manager.MirrorSettings{})
+
+	profile := manager.LauncherProfile{JAR: filepath.Join(t.TempDir(), "client.jar")}
+	profile.Version.Downloads = map[string]manager.Artifact{"client": {
+		Url:  "https://piston-data.mojang.com/v1/objects/abc/client.jar",
+		SHA1: fmt.Sprintf("%x", sha1.Sum(content)),
+	}}
+	start := time.Now()
+	if err := profile.InstallMinecraft(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) > 200*time.Millisecond {
+		t.Error("permanent failure was retried")
+	}
+}
+
+type progressRecorder struct {
+	mu       sync.Mutex
+	finished bool // a complete progress was reported before the download finished
+}
+
+func (r *progressRecorder) Handle(event events.ProgressUpdateEventPayload) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if event.Message != "Done" && event.BytesTotal > 0 && event.BytesDone >= event.BytesTotal {
+		r.finished = true
+	}
+}
+
+func TestRetriedDownloadProgress(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	content := bytes.Repeat([]byte("minecraft"), 256)
+	half := len(content) / 2
+	recorder := &progressRecorder{}
+	events.ProgressUpdateEvent.Register(recorder)
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			// the connection drops halfway through the body
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			_, _ = w.Write(content[:half])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", half, len(content)-1, len(content)))
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)-half))
+		w.WriteHeader(http.StatusPartialContent)
+		w.(http.Flusher).Flush()
+		time.Sleep(300 * time.Millisecond) // the resumed part has been reported by now
+		recorder.mu.Lock()
+		if recorder.finished {
+			t.Error("resumed bytes counted twice")
+		}
+		recorder.mu.Unlock()
+		_, _ = w.Write(content[half:])
+	}))
+	defer server.Close()
+
+	profile := manager.LauncherProfile{JAR: filepath.Join(t.TempDir(), "client.jar")}
+	profile.Version.Downloads = map[string]manager.Artifact{"client": {
+		Url:  server.URL + "/client.jar",
+		SHA1: fmt.Sprintf("%x", sha1.Sum(content)),
+		Size: int64(len(content)),
+	}}
+	if _, err := profile.Repair(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Error("expected the download to be resumed once, got attempts:", attempts)
+	}
+}
+
+func TestCancelledDownload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "2048")
+		_, _ = w.Write(make([]byte, 1024))
+		w.(http.Flusher).Flush()
+		cancel() // the client gives up halfway through the body
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	profile := manager.LauncherProfile{JAR: filepath.Join(t.TempDir(), "client.jar")}
+	profile.Version.Downloads = map[string]manager.Artifact{"client": {Url: server.URL + "/client.jar", Size: 2048}}
+	start := time.Now()
+	err := profile.InstallMinecraft(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal("expected the download to be cancelled, got", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("cancellation was not prompt")
+	}
+	for _, path := range []string{profile.JAR, profile.JAR + ".part"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Error("partial file left behind:", path)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"github.com/wailsapp/wails/v2/pkg/logger"
+	"launcher/logging"
+	"launcher/manager"
+	"launcher/manager/comp"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInstallFabric(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	logging.Logger = logger.NewDefaultLogger()
+
+	services := newFakeServices(t)
+	services.serveVanilla("1.19", nil)
+	services.serve("/v2/versions/loader/1.19", []byte(`[
+		{"loader": {"version": "0.15.0-beta", "stable": false}},
+		{"loader": {"version": "0.14.8", "stable": true}}
+	]`))
+	services.serveFabricLoader("1.19", "0.14.8")
+
+	if err := manager.InstallProfile(context.Background(), "1.19", manager.LoaderFabric); err != nil {
+		t.Fatal(err)
+	}
+	installed, err := manager.ReadInstallJournal()
+	if err != nil || installed.Profile != "fabric-loader-0.14.8-1.19" {
+		t.Error("stable loader not installed", installed, err)
+	}
+	path := filepath.Join(comp.GetLibraryPath(), "net", "fabricmc", "fabric-loader", "0.14.8", "fabric-loader-0.14.8.jar")
+	if b, err := os.ReadFile(path); err != nil || string(b) != "loader 0.14.8" {
+		t.Error("loader library not installed", err)
+	}
+
+	// a library whose checksum does not match is rejected
+	services.serve("/maven/net/fabricmc/fabric-loader/0.14.8/fabric-loader-0.14.8.jar.sha1", []byte(sha1Hex([]byte("other"))))
+	_ = os.Remove(path)
+	if err := manager.InstallProfile(context.Background(), "1.19", manager.LoaderFabric); err == nil {
+		t.Error("library with mismatching checksum installed")
+	}
+}
+
+func TestInstallFabricThroughMirror(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	logging.Logger = logger.NewDefaultLogger()
+
+	services := newFakeServices(t)
+	services.serveVanilla("1.19", nil)
+	services.serve("/v2/versions/loader/1.19", []byte(`[{"loader": {"version": "0.14.8", "stable": true}}]`))
+	services.serve("/v2/versions/loader/1.19/0.14.8/profile/json", []byte(`{
+		"id": "fabric-loader-0.14.8-1.19",
+		"inheritsFrom": "1.19",
+		"mainClass": "net.fabricmc.loader.impl.launch.knot.KnotClient",
+		"libraries": [{"name": "net.fabricmc:fabric-loader:0.14.8", "url": "https://maven.fabricmc.net/"}]
+	}`))
+	// only the mirror is reachable, it serves the checksum as well as the library
+	loader := []byte("loader")
+	path := "/mirror/net/fabricmc/fabric-loader/0.14.8/fabric-loader-0.14.8.jar"
+	services.serve(path, loader)
+	services.serve(path+".sha256", []byte(fmt.Sprintf("%x", sha256.Sum256(loader))))
+	manager.SetMirrors(manager.MirrorSettings{Maven: []string{services.URL + "/mirror"}})
+	defer manager.SetMirrors(manager.MirrorSettings{})
+
+	if err := manager.InstallProfile(context.Background(), "1.19", manager.LoaderFabric); err != nil {
+		t.Fatal(err)
+	}
+	installed := filepath.Join(comp.GetLibraryPath(), "net", "fabricmc", "fabric-loader", "0.14.8", "fabric-loader-0.14.8.jar")
+	if b, err := os.ReadFile(installed); err != nil || string(b) != "loader" {
+		t.Error("loader library not installed from the mirror", err)
+	}
+}
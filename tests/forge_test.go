@@ -0,0 +1,259 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/wailsapp/wails/v2/pkg/logger"
+	"launcher/logging"
+	"launcher/manager"
+	"launcher/manager/comp"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestInstallForge(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	logging.Logger = logger.NewDefaultLogger()
+
+	tools := []byte("tools")
+	patched := []byte("patched")
+	srg := []byte("srg")
+	services := newFakeServices(t)
+	services.serveVanilla("1.19", nil)
+	services.serve("/net/minecraftforge/forge/maven-metadata.xml", []byte(`<metadata><versioning><versions>
+		<version>1.18.2-40.0.0</version><version>1.19-41.0.9</version><version>1.19-41.1.0</version>
+	</versions></versioning></metadata>`))
+	// the patched client is bundled here, so that its processor finds its output present and java is not needed
+	installer := zipFiles(t, map[string][]byte{
+		"install_profile.json": []byte(fmt.Sprintf(`{
+			"spec": 1,
+			"version": "1.19-forge-41.1.0",
+			"json": "/version.json",
+			"minecraft": "1.19",
+			"data": {
+				"MC_SRG": {"client": "[net.minecraft:client:1.19-20220607.102129:srg]", "server": "[net.minecraft:server:1.19-20220607.102129:srg]"},
+				"PATCHED": {"client": "[net.minecraftforge:forge:1.19-41.1.0:client]", "server": "[net.minecraftforge:forge:1.19-41.1.0:server]"},
+				"PATCHED_SHA": {"client": "'%s'", "server": "''"}
+			},
+			"processors": [
+				{"sides": ["server"], "jar": "net.minecraftforge:missing:1.0", "args": ["--side", "{SIDE}"]},
+				{"jar": "net.minecraftforge:installertools:1.0", "args": ["--input", "{MINECRAFT_JAR}", "--output", "{PATCHED}"],
+					"outputs": {"{PATCHED}": "{PATCHED_SHA}"}}
+			],
+			"libraries": [{"name": "net.minecraftforge:installertools:1.0", "downloads": {"artifact": {
+				"path": "net/minecraftforge/installertools/1.0/installertools-1.0.jar", "url": "%s", "sha1": "%s"}}}]
+		}`, sha1Hex(patched), services.serve("/maven/installertools-1.0.jar", tools), sha1Hex(tools))),
+		"version.json": []byte(fmt.Sprintf(`{
+			"id": "1.19-forge-41.1.0",
+			"inheritsFrom": "1.19",
+			"mainClass": "cpw.mods.bootstraplauncher.BootstrapLauncher",
+			"libraries": [{"name": "net.minecraftforge:forge:1.19-41.1.0:client", "downloads": {"artifact": {
+				"path": "net/minecraftforge/forge/1.19-41.1.0/forge-1.19-41.1.0-client.jar", "url": "", "sha1": "%s"}}}]
+		}`, sha1Hex(patched))),
+		"maven/net/minecraftforge/forge/1.19-41.1.0/forge-1.19-41.1.0-client.jar":             patched,
+		"maven/net/minecraft/client/1.19-20220607.102129/client-1.19-20220607.102129-srg.jar": srg,
+	})
+	services.serveArtifact("/net/minecraftforge/forge/1.19-41.1.0/forge-1.19-41.1.0-installer.jar", installer)
+
+	builds, err := manager.GetForgeVersions(context.Background(), "1.19")
+	if err != nil || len(builds) != 2 || builds[0] != "1.19-41.1.0" {
+		t.Fatal("unexpected forge versions", builds, err)
+	}
+
+	if err := manager.InstallProfile(context.Background(), "1.19", manager.LoaderForge); err != nil {
+		t.Fatal(err)
+	}
+	installed, err := manager.ReadInstallJournal()
+	if err != nil || installed.Profile != "1.19-forge-41.1.0" {
+		t.Error("newest forge build not installed", installed, err)
+	}
+	for path, data := range map[string][]byte{
+		filepath.Join("net", "minecraftforge", "installertools", "1.0", "installertools-1.0.jar"):      tools,
+		filepath.Join("net", "minecraftforge", "forge", "1.19-41.1.0", "forge-1.19-41.1.0-client.jar"): patched,
+	} {
+		if b, err := os.ReadFile(filepath.Join(comp.GetLibraryPath(), path)); err != nil || !bytes.Equal(b, data) {
+			t.Error("library not installed:", path, err)
+		}
+	}
+
+	// the files the processors produce are only named by the launch arguments, but must survive collecting garbage
+	srgPath := filepath.Join(comp.GetLibraryPath(), "net", "minecraft", "client", "1.19-20220607.102129", "client-1.19-20220607.102129-srg.jar")
+	if _, err := manager.CollectGarbage(false); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{srgPath, filepath.Join(comp.GetLibraryPath(), "net", "minecraftforge", "forge", "1.19-41.1.0", "forge-1.19-41.1.0-client.jar")} {
+		if _, err := os.Stat(path); err != nil {
+			t.Error("installer output collected:", path, err)
+		}
+	}
+
+	// no repository serves the patched client, the repair runs the installer again to generate it
+	client := filepath.Join(comp.GetLibraryPath(), "net", "minecraftforge", "forge", "1.19-41.1.0", "forge-1.19-41.1.0-client.jar")
+	if err := os.Remove(client); err != nil {
+		t.Fatal(err)
+	}
+	var game *manager.LauncherProfile
+	for _, g := range manager.Explore() {
+		if g.Name == "1.19-forge-41.1.0" {
+			g := g
+			game = &g
+		}
+	}
+	if game == nil || game.Loader != manager.LoaderForge {
+		t.Fatal("forge profile not found", game)
+	}
+	report, err := game.Repair(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Libraries) != 1 {
+		t.Error("missing client not reported", report)
+	}
+	if b, err := os.ReadFile(client); err != nil || !bytes.Equal(b, patched) {
+		t.Error("patched client not repaired", err)
+	}
+}
+
+func TestRunForgeProcessor(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake java executable is a shell script")
+	}
+	t.Setenv("HOME", t.TempDir())
+	logging.Logger = logger.NewDefaultLogger()
+
+	// java is a script writing $FAKE_JAVA_OUTPUT to the path after --output, it records its arguments next to itself
+	java := []byte(`#!/bin/sh
+echo "$@" > "$0.args"
+while [ $# -gt 0 ]; do
+	if [ "$1" = "--output" ]; then mkdir -p "$(dirname "$2")" && printf '%s' "$FAKE_JAVA_OUTPUT" > "$2"; fi
+	shift
+done
+`)
+	tools := zipFiles(t, map[string][]byte{
+		"META-INF/MANIFEST.MF": []byte("Manifest-Version: 1.0\r\nMain-Class: net.minecraftforge.installertools.ConsoleTool\r\n"),
+	})
+	srgutils := []byte("srgutils")
+	patched := []byte("patched")
+	services := newFakeServices(t)
+	services.serveVanilla("1.19", nil)
+	services.serveJavaRuntime("java-runtime-gamma", []byte(fmt.Sprintf(`{"files": {
+		"bin": {"type": "directory"},
+		"bin/java": {"type": "file", "executable": true, "downloads": {"raw": {"url": "%s", "sha1": "%s", "size": %d}}}
+	}}`, services.serve("/runtimes/java", java), sha1Hex(java), len(java))))
+	services.serve("/net/minecraftforge/forge/maven-metadata.xml", []byte(`<metadata><versioning><versions>
+		<version>1.19-41.1.0</version>
+	</versions></versioning></metadata>`))
+	installer := zipFiles(t, map[string][]byte{
+		"install_profile.json": []byte(fmt.Sprintf(`{
+			"spec": 1,
+			"version": "1.19-forge-41.1.0",
+			"json": "/version.json",
+			"minecraft": "1.19",
+			"data": {
+				"PATCHED": {"client": "[net.minecraftforge:forge:1.19-41.1.0:client]", "server": "[net.minecraftforge:forge:1.19-41.1.0:server]"},
+				"PATCHED_SHA": {"client": "'%s'", "server": "''"}
+			},
+			"processors": [
+				{"jar": "net.minecraftforge:installertools:1.0", "classpath": ["net.minecraftforge:srgutils:1.0"],
+					"args": ["--input", "{MINECRAFT_JAR}", "--output", "{PATCHED}"], "outputs": {"{PATCHED}": "{PATCHED_SHA}"}}
+			],
+			"libraries": [
+				{"name": "net.minecraftforge:installertools:1.0", "downloads": {"artifact": {
+					"path": "net/minecraftforge/installertools/1.0/installertools-1.0.jar", "url": "%s", "sha1": "%s"}}},
+				{"name": "net.minecraftforge:srgutils:1.0", "downloads": {"artifact": {
+					"path": "net/minecraftforge/srgutils/1.0/srgutils-1.0.jar", "url": "%s", "sha1": "%s"}}}
+			]
+		}`, sha1Hex(patched), services.serve("/maven/installertools-1.0.jar", tools), sha1Hex(tools),
+			services.serve("/maven/srgutils-1.0.jar", srgutils), sha1Hex(srgutils))),
+		"version.json": []byte(fmt.Sprintf(`{
+			"id": "1.19-forge-41.1.0",
+			"inheritsFrom": "1.19",
+			"mainClass": "cpw.mods.bootstraplauncher.BootstrapLauncher",
+			"libraries": [{"name": "net.minecraftforge:forge:1.19-41.1.0:client", "downloads": {"artifact": {
+				"path": "net/minecraftforge/forge/1.19-41.1.0/forge-1.19-41.1.0-client.jar", "url": "", "sha1": "%s"}}}]
+		}`, sha1Hex(patched))),
+	})
+	services.serveArtifact("/net/minecraftforge/forge/1.19-41.1.0/forge-1.19-41.1.0-installer.jar", installer)
+	client := filepath.Join(comp.GetLibraryPath(), "net", "minecraftforge", "forge", "1.19-41.1.0", "forge-1.19-41.1.0-client.jar")
+
+	// an output not matching its hash after the run fails the install and is not left in place
+	t.Setenv("FAKE_JAVA_OUTPUT", "corrupt")
+	if err := manager.InstallProfile(context.Background(), "1.19", manager.LoaderForge); err == nil {
+		t.Fatal("install succeeded with a corrupt processor output")
+	}
+	if _, err := os.Stat(client); !os.IsNotExist(err) {
+		t.Error("corrupt processor output left in place", err)
+	}
+
+	t.Setenv("FAKE_JAVA_OUTPUT", string(patched))
+	if err := manager.InstallProfile(context.Background(), "1.19", manager.LoaderForge); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(client); err != nil || !bytes.Equal(b, patched) {
+		t.Error("processor output not installed", err)
+	}
+	// the processor runs with the managed runtime, its jar and classpath, and the main class of its manifest
+	args, err := os.ReadFile(comp.GetJavaExecutable(manager.GetJavaRuntimePath("java-runtime-gamma")) + ".args")
+	if err != nil {
+		t.Fatal("processor not run with the java runtime", err)
+	}
+	classpath := strings.Join([]string{
+		filepath.Join(comp.GetLibraryPath(), "net", "minecraftforge", "installertools", "1.0", "installertools-1.0.jar"),
+		filepath.Join(comp.GetLibraryPath(), "net", "minecraftforge", "srgutils", "1.0", "srgutils-1.0.jar"),
+	}, string(comp.GetSeparator()))
+	expected := fmt.Sprintf("-cp %s net.minecraftforge.installertools.ConsoleTool --input %s --output %s",
+		classpath, manager.GetVersionJARPath("1.19"), client)
+	if got := strings.TrimSpace(string(args)); got != expected {
+		t.Errorf("unexpected processor invocation:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestGetNeoForgeVersions(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	services := newFakeServices(t)
+	services.serve("/net/neoforged/neoforge/maven-metadata.xml", []byte(`<metadata><versioning><versions>
+		<version>20.4.80-beta</version><version>20.4.237</version><version>21.0.167</version>
+	</versions></versioning></metadata>`))
+
+	for version, newest := range map[string]string{
+		"1.21":   "21.0.167",
+		"1.20.4": "20.4.237",
+		"24w14a": "", // snapshots are not supported
+		"1.19.x": "",
+		"2.0":    "",
+	} {
+		builds, err := manager.GetNeoForgeVersions(context.Background(), version)
+		if newest == "" {
+			if err == nil {
+				t.Error("builds found for unsupported version", version, builds)
+			}
+			continue
+		}
+		if err != nil || builds[0] != newest {
+			t.Error("unexpected neoforge versions for", version, builds, err)
+		}
+	}
+}
+
+func zipFiles(t *testing.T, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, data := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
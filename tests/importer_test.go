@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"launcher/manager"
+	"launcher/manager/comp"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImportMinecraft(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	src := t.TempDir()
+	write := func(rel string, data string) {
+		path := filepath.Join(src, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	index := `{"objects": {}}`
+	version := fmt.Sprintf(`{
+		"id": "1.19",
+		"assetIndex": {"id": "1.19", "sha1": "%s"},
+		"downloads": {"client": {"sha1": "%s"}},
+		"libraries": [
+			{"name": "com.example:lib:1.0", "downloads": {"artifact": {"path": "com/example/lib/1.0/lib-1.0.jar", "sha1": "%s"}}},
+			{"name": "com.example:corrupt:1.0", "downloads": {"artifact": {"path": "com/example/corrupt/1.0/corrupt-1.0.jar", "sha1": "%s"}}}
+		]
+	}`, sha1Hex([]byte(index)), sha1Hex([]byte("client")), sha1Hex([]byte("library")), sha1Hex([]byte("corrupt")))
+	write("versions/1.19/1.19.json", version)
+	write("versions/1.19/1.19.jar", "client")
+	write("versions/fabric-loader-1.19/fabric-loader-1.19.json", `{"id": "fabric-loader-1.19", "inheritsFrom": "1.19"}`)
+	write("assets/indexes/1.19.json", index)
+	write("assets/objects/"+sha1Hex([]byte("icon"))[0:2]+"/"+sha1Hex([]byte("icon")), "icon")
+	write("assets/objects/"+sha1Hex([]byte("sound"))[0:2]+"/"+sha1Hex([]byte("sound")), "modified")
+	write("libraries/com/example/lib/1.0/lib-1.0.jar", "library")
+	write("libraries/com/example/corrupt/1.0/corrupt-1.0.jar", "modified")
+
+	services := newFakeServices(t)
+	services.serve("/mc/game/version_manifest_v2.json", []byte(fmt.Sprintf(`{"latest": {}, "versions": [{"id": "1.19", "sha1": "%s"}]}`, sha1Hex([]byte(version)))))
+
+	report, err := manager.ImportMinecraft(context.Background(), src, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Imported != 5 || report.Rejected != 2 || report.Present != 0 {
+		t.Error("unexpected report", report)
+	}
+
+	icon := manager.Asset{Hash: sha1Hex([]byte("icon"))}
+	imported := map[string]string{
+		manager.GetVersionFilePath("1.19"):                "versions/1.19/1.19.json",
+		manager.GetVersionJARPath("1.19"):                 "versions/1.19/1.19.jar",
+		filepath.Join(comp.GetIndexesPath(), "1.19.json"): "assets/indexes/1.19.json",
+		icon.GetObjectPath():                              "assets/objects/" + sha1Hex([]byte("icon"))[0:2] + "/" + sha1Hex([]byte("icon")),
+		filepath.Join(comp.GetLibraryPath(), "com", "example", "lib", "1.0", "lib-1.0.jar"): "libraries/com/example/lib/1.0/lib-1.0.jar",
+	}
+	for dst, rel := range imported {
+		a, err := os.Stat(dst)
+		if err != nil {
+			t.Error("not imported:", rel)
+			continue
+		}
+		b, _ := os.Stat(filepath.Join(src, filepath.FromSlash(rel)))
+		if report.Linked == report.Imported && !os.SameFile(a, b) {
+			t.Error("not linked:", rel)
+		}
+	}
+	if _, err := os.Stat(manager.GetVersionFilePath("fabric-loader-1.19")); !os.IsNotExist(err) {
+		t.Error("unverifiable loader profile imported")
+	}
+	if _, err := os.Stat(filepath.Join(comp.GetLibraryPath(), "com", "example", "corrupt")); !os.IsNotExist(err) {
+		t.Error("corrupt library imported")
+	}
+
+	report, err = manager.ImportMinecraft(context.Background(), src, false)
+	if err != nil || report.Imported != 0 || report.Present != 5 {
+		t.Error("valid files imported again", report, err)
+	}
+}
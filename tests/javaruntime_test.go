@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/ulikunitz/xz/lzma"
+	"launcher/manager"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInstallJavaRuntime(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	java := []byte("java binary")
+	release := []byte("JAVA_VERSION=17")
+	var compressed bytes.Buffer
+	w, err := lzma.NewWriter(&compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write(java)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	services := newFakeServices(t)
+	services.serveJavaRuntime("java-runtime-gamma", []byte(fmt.Sprintf(`{"files": {
+		"bin": {"type": "directory"},
+		"bin/java": {"type": "file", "executable": true, "downloads": {
+			"raw": {"url": "%[1]s/java", "sha1": "%[2]s", "size": %[3]d},
+			"lzma": {"url": "%[4]s", "sha1": "%[5]s", "size": %[6]d}}},
+		"release": {"type": "file", "downloads": {"raw": {"url": "%[1]s/release", "sha1": "%[7]s", "size": %[8]d}}}
+	}}`, services.URL, sha1Hex(java), len(java), services.serve("/java.lzma", compressed.Bytes()), sha1Hex(compressed.Bytes()),
+		compressed.Len(), sha1Hex(release), len(release))))
+
+	component := manager.JavaVersion{Component: "java-runtime-gamma", MajorVersion: 17}
+	dir := manager.GetJavaRuntimePath(component.Component)
+	// the release file is missing, the install fails without leaving an unverified file behind
+	if _, err := manager.InstallJavaRuntime(context.Background(), component); err == nil {
+		t.Fatal("runtime installed without all its files")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "release")); !os.IsNotExist(err) {
+		t.Error("failed download left in place")
+	}
+
+	services.serve("/release", release)
+	if _, err := manager.InstallJavaRuntime(context.Background(), component); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "bin", "java")); err != nil || !bytes.Equal(b, java) {
+		t.Error("compressed file not installed", err)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "release")); err != nil || !bytes.Equal(b, release) {
+		t.Error("raw file not installed", err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "*", "*.lzma*"))
+	if len(matches) != 0 {
+		t.Error("archives left behind", matches)
+	}
+}
@@ -1,24 +1,68 @@
 package tests
 
 import (
-	"fmt"
 	"launcher/manager"
-	"os/exec"
-	"strings"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 )
 
 func TestJavaVersionCheck(t *testing.T) {
-	cmd := exec.Command("java", "--version")
-	o, err := cmd.CombinedOutput()
+	installations := manager.DiscoverJava()
+	if len(installations) == 0 {
+		t.Fatal("no java installation found")
+	}
+	for _, java := range installations {
+		t.Log(java.Home, java.Vendor, java.Version)
+		if manager.CompareVersion("17.0.0", java.Version, manager.PrecisionFull) {
+			return
+		}
+	}
+	t.Error("invalid java version")
+}
+
+func TestParseJavaVersion(t *testing.T) {
+	cases := map[string][2]int{
+		"1.8.0_312": {8, 0},
+		"17.0.2":    {17, 0},
+		"17.0.2.1":  {17, 0},
+		"21":        {21, 0},
+		"11.1.4":    {11, 1},
+		"19-ea":     {19, 0},
+	}
+	for version, expected := range cases {
+		major, minor, ok := manager.ParseJavaVersion(version)
+		if !ok || major != expected[0] || minor != expected[1] {
+			t.Errorf("%s parsed as %d.%d", version, major, minor)
+		}
+	}
+	if _, _, ok := manager.ParseJavaVersion("openjdk"); ok {
+		t.Error("invalid version accepted")
+	}
+	if !manager.CompareVersion("17.0.0", "17.0.2.1", manager.PrecisionFull) {
+		t.Error("four part version rejected")
+	}
+}
+
+func TestInspectJava(t *testing.T) {
+	home := t.TempDir()
+	exe := filepath.Join(home, "bin", "java")
+	if runtime.GOOS == "windows" {
+		exe += ".exe"
+	}
+	_ = os.MkdirAll(filepath.Dir(exe), os.ModePerm)
+	_ = os.WriteFile(exe, nil, 0755)
+	err := os.WriteFile(filepath.Join(home, "release"), []byte("IMPLEMENTOR=\"Eclipse Adoptium\"\nJAVA_VERSION=\"17.0.2\"\nOS_ARCH=\"aarch64\"\n"), 0644)
 	if err != nil {
-		t.Error("execution failed")
-	}
-	str := string(o)
-	words := strings.Split(str, " ")
-	version := words[1]
-	fmt.Println(version)
-	if !manager.CompareVersion("17.0.0", version, manager.PrecisionFull) {
-		t.Error("invalid java version")
+		t.Fatal(err)
+	}
+
+	java, err := manager.InspectJava(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if java.Vendor != "Eclipse Adoptium" || java.Major != 17 || java.Arch != "arm64" || java.Executable != exe {
+		t.Error("unexpected installation", java)
 	}
 }
@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/wailsapp/wails/v2/pkg/logger"
+	"launcher/logging"
+	"launcher/manager"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResumeInstall(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	logging.Logger = logger.NewDefaultLogger()
+
+	icon := []byte("icon")
+	index := []byte(fmt.Sprintf(`{"objects": {"icons/icon.png": {"hash": "%s", "size": 4}}}`, sha1Hex(icon)))
+	library := []byte("library")
+	services := newFakeServices(t)
+	services.serveVanilla("1.19", index, fmt.Sprintf(`"libraries": [{"name": "com.example:lib:1.0", "downloads": {"artifact": {
+		"path": "com/example/lib/1.0/lib-1.0.jar", "url": "%s/lib.jar", "sha1": "%s", "size": 7}}}]`, services.URL, sha1Hex(library)))
+	services.serve("/"+sha1Hex(icon)[0:2]+"/"+sha1Hex(icon), icon)
+	services.serve("/v2/versions/loader/1.19", []byte(`[{"loader": {"version": "0.14.8", "stable": true}}]`))
+	services.serveFabricLoader("1.19", "0.14.8")
+
+	// the library is missing, the install stops at its last step
+	if err := manager.InstallProfile(context.Background(), "1.19", manager.LoaderFabric); err == nil {
+		t.Fatal("install succeeded without its library")
+	}
+	journal, err := manager.ReadInstallJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if journal.Completed || !reflect.DeepEqual(journal.PendingSteps(), []string{manager.StepLibraries}) {
+		t.Fatal("unexpected journal", journal)
+	}
+
+	services.serve("/lib.jar", library)
+	services.takeRequests()
+	if err := manager.InstallProfile(context.Background(), "1.19", manager.LoaderFabric); err != nil {
+		t.Fatal(err)
+	}
+	// only the libraries step runs again, it verifies the loader library against its checksum too
+	downloaded := false
+	requested := services.takeRequests()
+	for _, path := range requested {
+		downloaded = downloaded || path == "/lib.jar"
+		if path != "/lib.jar" && !strings.HasPrefix(path, "/maven/") {
+			t.Error("completed steps ran again", requested)
+		}
+	}
+	if !downloaded {
+		t.Error("missing library not downloaded", requested)
+	}
+	journal, err = manager.ReadInstallJournal()
+	if err != nil || !journal.Completed {
+		t.Error("install not recorded as completed", journal, err)
+	}
+}
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"context"
 	"github.com/pkg/errors"
 	"github.com/wailsapp/wails/v2/pkg/logger"
 	"launcher/api/microsoft"
@@ -13,7 +14,7 @@ func Test(t *testing.T) {
 	logging.Logger = logger.NewDefaultLogger()
 
 	t.Log("Installing profile")
-	err := manager.InstallTheOnlyProfile("/home/martin/.genecraft")
+	err := manager.InstallProfile(context.Background(), "1.19", manager.LoaderFabric)
 	if err != nil {
 		t.Error(errors.WithMessage(err, "Failed to create profile"))
 		return
@@ -26,7 +27,7 @@ func Test(t *testing.T) {
 		return
 	}
 	t.Log("Installing miencraft")
-	err = games[0].InstallMinecraft()
+	err = games[0].InstallMinecraft(context.Background())
 	if err != nil {
 		t.Error(errors.WithMessage(err, "Failed to install minecraft"))
 		return
@@ -50,7 +51,7 @@ func Test(t *testing.T) {
 	}
 
 	t.Log("Launching game")
-	err = games[0].Launch(manager.LauncherAuth{
+	err = games[0].Launch(context.Background(), manager.LauncherAuth{
 		Username:    profile.Name,
 		AccessToken: profile.AccessToken,
 		UUID:        profile.ID,
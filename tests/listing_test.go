@@ -0,0 +1,47 @@
+package tests
+
+import (
+	"encoding/json"
+	"launcher/manager"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListVersions(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var mf manager.Manifest
+	err := json.Unmarshal([]byte(`{
+		"latest": {"release": "1.19", "snapshot": "22w24a"},
+		"versions": [
+			{"id": "1.18.2", "type": "release", "releaseTime": "2022-02-28T10:42:45+00:00"},
+			{"id": "22w24a", "type": "snapshot", "releaseTime": "2022-06-15T16:24:16+00:00"},
+			{"id": "b1.7.3", "type": "old_beta", "releaseTime": "2011-07-07T22:00:00+00:00"},
+			{"id": "1.19", "type": "release", "releaseTime": "2022-06-07T09:42:18+00:00"}
+		]
+	}`), &mf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range []string{manager.GetVersionFilePath("1.19"), manager.GetVersionJARPath("1.19")} {
+		_ = os.MkdirAll(filepath.Dir(file), os.ModePerm)
+		if err := os.WriteFile(file, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	releases := mf.ListVersions(manager.VersionRelease)
+	if len(releases) != 2 || releases[0].ID != "1.19" || releases[1].ID != "1.18.2" {
+		t.Fatal("unexpected releases", releases)
+	}
+	if !releases[0].Installed || releases[1].Installed {
+		t.Error("installed flag not set correctly")
+	}
+
+	all := mf.ListVersions()
+	if len(all) != 4 || all[0].ID != "22w24a" || all[3].ID != "b1.7.3" {
+		t.Error("unexpected versions", all)
+	}
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"context"
+	"github.com/wailsapp/wails/v2/pkg/logger"
+	"launcher/logging"
+	"launcher/manager"
+	"launcher/manager/comp"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestChangeLoader(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	logging.Logger = logger.NewDefaultLogger()
+
+	services := newFakeServices(t)
+	services.serveVanilla("1.19", nil)
+	services.serve("/v2/versions/loader/1.19", []byte(`[
+		{"loader": {"version": "0.14.9", "stable": true}},
+		{"loader": {"version": "0.14.8", "stable": true}}
+	]`))
+	services.serveFabricLoader("1.19", "0.14.8")
+	services.serveFabricLoader("1.19", "0.14.9")
+	if err := manager.InstallProfile(context.Background(), "1.19", manager.LoaderFabric); err != nil {
+		t.Fatal(err)
+	}
+	mod := filepath.Join(comp.GetLauncherRoot(), "mods", "mod.jar")
+	if err := os.MkdirAll(filepath.Dir(mod), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(mod, []byte("mod"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var profile manager.LauncherProfile
+	for _, p := range manager.Explore() {
+		if p.Loader != "" {
+			profile = p
+		}
+	}
+	if profile.Loader != manager.LoaderFabric || profile.LoaderVersion != "0.14.9" {
+		t.Fatal("unexpected loader", profile.Loader, profile.LoaderVersion)
+	}
+	builds, err := manager.GetLoaderVersions(context.Background(), profile.Loader, "1.19")
+	if err != nil || !reflect.DeepEqual(builds, []string{"0.14.9", "0.14.8"}) {
+		t.Fatal("unexpected loader versions", builds, err)
+	}
+
+	settings := manager.LauncherClientSettings{JavaHomes: map[string]string{profile.Name: "/opt/java"}}
+	previous := profile.Name
+	if err := profile.ChangeLoader(context.Background(), "0.14.8"); err != nil {
+		t.Fatal(err)
+	}
+	settings.RenameProfile(previous, profile.Name)
+	if !reflect.DeepEqual(settings.JavaHomes, map[string]string{"fabric-loader-0.14.8-1.19": "/opt/java"}) {
+		t.Error("java pin not moved to the renamed profile", settings.JavaHomes)
+	}
+	if profile.Name != "fabric-loader-0.14.8-1.19" || profile.LoaderVersion != "0.14.8" {
+		t.Error("profile not switched", profile.Name, profile.LoaderVersion)
+	}
+	if _, err := os.Stat(manager.GetVersionFilePath("fabric-loader-0.14.9-1.19")); !os.IsNotExist(err) {
+		t.Error("previous loader profile kept")
+	}
+	path := filepath.Join(comp.GetLibraryPath(), "net", "fabricmc", "fabric-loader", "0.14.8", "fabric-loader-0.14.8.jar")
+	if _, err := os.Stat(path); err != nil {
+		t.Error("loader library not installed", err)
+	}
+	if _, err := os.Stat(mod); err != nil {
+		t.Error("mods removed", err)
+	}
+	installed, err := manager.ReadInstallJournal()
+	if err != nil || installed.Profile != profile.Name {
+		t.Error("journal not switched", installed, err)
+	}
+}
@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"launcher/manager/maven"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMavenCoordinate(t *testing.T) {
+	paths := map[string]string{
+		"org.ow2.asm:asm:9.3":                             "org/ow2/asm/asm/9.3/asm-9.3.jar",
+		"org.lwjgl:lwjgl:3.3.1:natives-linux":             "org/lwjgl/lwjgl/3.3.1/lwjgl-3.3.1-natives-linux.jar",
+		"de.oceanlabs.mcp:mcp_config:1.19-20220607@zip":   "de/oceanlabs/mcp/mcp_config/1.19-20220607/mcp_config-1.19-20220607.zip",
+		"net.minecraft:client:1.19-20220607:mappings@txt": "net/minecraft/client/1.19-20220607/client-1.19-20220607-mappings.txt",
+	}
+	for name, path := range paths {
+		c, err := maven.Parse(name)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if c.Path() != path {
+			t.Error("unexpected path of", name, c.Path())
+		}
+		if c.String() != name {
+			t.Error("unexpected name", c.String())
+		}
+	}
+	for _, name := range []string{"org.ow2.asm:asm", "org.ow2.asm::9.3", "a:b:c:d:e"} {
+		if _, err := maven.Parse(name); err == nil {
+			t.Error("invalid coordinate parsed:", name)
+		}
+	}
+}
+
+func TestMavenResolve(t *testing.T) {
+	artifact := []byte("artifact")
+	path := "/com/example/lib/1.0/lib-1.0.jar"
+	files := map[string][]byte{
+		// the declared repository does not publish the artifact
+		"/corrupt" + path:           []byte("corrupt"),
+		"/corrupt" + path + ".sha1": []byte(fmt.Sprintf("%x", sha1.Sum(artifact))),
+		"/valid" + path:             artifact,
+		"/valid" + path + ".sha256": []byte(fmt.Sprintf("%x  lib-1.0.jar", sha256.Sum256(artifact))),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(b)
+	}))
+	defer server.Close()
+
+	r := maven.Resolver{Repositories: []string{server.URL + "/corrupt", server.URL + "/valid/"}}
+	c, _ := maven.Parse("com.example:lib:1.0")
+	dst := filepath.Join(t.TempDir(), "lib.jar")
+	downloaded, err := r.Resolve(context.Background(), c, server.URL+"/declared", dst)
+	if err != nil || !downloaded {
+		t.Fatal("artifact not resolved", err)
+	}
+	if b, err := os.ReadFile(dst); err != nil || string(b) != "artifact" {
+		t.Error("unexpected artifact", string(b), err)
+	}
+	downloaded, err = r.Resolve(context.Background(), c, server.URL+"/declared", dst)
+	if err != nil || downloaded {
+		t.Error("valid artifact downloaded again", err)
+	}
+
+	r.Repositories = []string{server.URL + "/corrupt"}
+	corrupt := filepath.Join(t.TempDir(), "lib.jar")
+	if _, err := r.Resolve(context.Background(), c, "", corrupt); err == nil {
+		t.Error("artifact not matching its checksum resolved")
+	}
+	if _, err := os.Stat(corrupt); !os.IsNotExist(err) {
+		t.Error("artifact not matching its checksum moved into place")
+	}
+
+	// the hooks serve checksums and artifacts, as mirrors do for the launcher
+	var fetched []string
+	r = maven.Resolver{
+		Fetch: func(ctx context.Context, address string) ([]byte, error) {
+			fetched = append(fetched, address)
+			return files[strings.Replace(address, "https://repo.example", "/valid", 1)], nil
+		},
+		Download: func(ctx context.Context, address string, path string, sum maven.Checksum) error {
+			if sum.Algorithm != "sha256" || sum.Hash != fmt.Sprintf("%x", sha256.Sum256(artifact)) {
+				t.Error("unexpected checksum", sum)
+			}
+			return os.WriteFile(path, artifact, 0644)
+		},
+	}
+	dst = filepath.Join(t.TempDir(), "lib.jar")
+	if _, err := r.Resolve(context.Background(), c, "https://repo.example", dst); err != nil {
+		t.Error("artifact not resolved through the hooks", err)
+	}
+	if len(fetched) != 1 || fetched[0] != "https://repo.example"+path+".sha256" {
+		t.Error("unexpected checksum requests", fetched)
+	}
+}
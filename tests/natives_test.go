@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"launcher/manager"
+	"launcher/manager/comp"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtractNatives(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var ver manager.Version
+	err := json.Unmarshal([]byte(`{
+		"id": "1.8.9",
+		"libraries": [{
+			"name": "org.lwjgl.lwjgl:lwjgl-platform:2.9.4",
+			"natives": {"linux": "natives-linux", "windows": "natives-windows-${arch}"},
+			"extract": {"exclude": ["META-INF/"]}
+		}]
+	}`), &ver)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := manager.Environment{OS: "windows", Arch: "x86"}
+	native, ok := ver.Libraries[0].GetNativeArtifact(env)
+	if !ok || filepath.Base(native.Path) != "lwjgl-platform-2.9.4-natives-windows-32.jar" {
+		t.Fatal("unexpected native artifact", native.Path)
+	}
+	if len(ver.GetLibraryPaths(comp.GetLibraryPath(), env)) != 0 {
+		t.Error("native only library should not be on the classpath")
+	}
+
+	archive := filepath.Join(comp.GetLibraryPath(), native.Path)
+	if err := os.MkdirAll(filepath.Dir(archive), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	h, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(h)
+	for _, name := range []string{"lwjgl.dll", "META-INF/MANIFEST.MF"} {
+		f, _ := w.Create(name)
+		_, _ = f.Write([]byte(name))
+	}
+	_ = w.Close()
+	_ = h.Close()
+
+	dir := t.TempDir()
+	if err := ver.ExtractNatives(dir, env); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "lwjgl.dll")); err != nil {
+		t.Error("native library not extracted")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "META-INF")); !os.IsNotExist(err) {
+		t.Error("excluded entries extracted")
+	}
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"launcher/api/backend"
+	"launcher/api/microsoft"
+	"launcher/manager"
+	"launcher/network"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFakeServices(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	version := []byte(`{"id":"1.19","type":"release","mainClass":"net.minecraft.client.main.Main"}`)
+	var agents []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, r.Header.Get("User-Agent"))
+		switch r.URL.Path {
+		case "/mc/game/version_manifest_v2.json":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"latest": map[string]string{"release": "1.19"},
+				"versions": []map[string]string{{
+					"id":   "1.19",
+					"type": "release",
+					"url":  server.URL + "/v1/packages/1.19.json",
+					"sha1": fmt.Sprintf("%x", sha1.Sum(version)),
+				}},
+			})
+		case "/v1/packages/1.19.json":
+			_, _ = w.Write(version)
+		case "/minecraft/profile":
+			if r.Header.Get("Authorization") != "Bearer token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"uuid","name":"Steve"}`))
+		case "/asset/index/":
+			_, _ = w.Write([]byte(`{"hat":{"id":"abcdef"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := network.Config{UserAgent: "test-agent", Endpoints: network.Endpoints{
+		Meta:      server.URL,
+		Minecraft: server.URL,
+		Backend:   server.URL + "/",
+	}}
+	for _, configure := range []func(network.Config) error{manager.Configure, microsoft.Configure, backend.Configure} {
+		if err := configure(cfg); err != nil {
+			t.Fatal(err)
+		}
+		defer configure(network.Config{})
+	}
+
+	mf, err := manager.GetManifest()
+	if err != nil || mf.Latest.Release != "1.19" {
+		t.Fatal("manifest not served by the fake server", err)
+	}
+	ver, err := mf.GetVersion("1.19")
+	if err != nil || ver.MainClass != "net.minecraft.client.main.Main" {
+		t.Fatal("version not served by the fake server", err)
+	}
+
+	handle := microsoft.MSAuthHandle{AccessToken: "token"}
+	profile, err := handle.GetMinecraftProfile()
+	if err != nil || profile.Name != "Steve" {
+		t.Fatal("profile not served by the fake server", err)
+	}
+
+	index, err := backend.GetWardrobeIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := index["hat"]
+	if link := entry.GetPreviewLink(); link != server.URL+"/asset/preview/ab/abcdef" {
+		t.Error("unexpected preview link", link)
+	}
+
+	for _, agent := range agents {
+		if agent != "test-agent" {
+			t.Error("unexpected user agent", agent)
+		}
+	}
+}
+
+func TestProxy(t *testing.T) {
+	var hosts []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts = append(hosts, r.URL.Host)
+		_, _ = w.Write([]byte("proxied"))
+	}))
+	defer proxy.Close()
+
+	client, err := network.NewClient(network.Config{Proxy: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := client.Get("http://meta.example.invalid/manifest.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = r.Body.Close()
+	if len(hosts) != 1 || hosts[0] != "meta.example.invalid" {
+		t.Error("request not sent through the proxy", hosts)
+	}
+
+	if _, err := network.NewClient(network.Config{Proxy: "ftp://proxy"}); err == nil {
+		t.Error("unsupported proxy scheme accepted")
+	}
+	if _, err := network.NewClient(network.Config{CABundle: "/nonexistent/ca.pem"}); err == nil {
+		t.Error("missing ca bundle accepted")
+	}
+}
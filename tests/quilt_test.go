@@ -0,0 +1,45 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/wailsapp/wails/v2/pkg/logger"
+	"launcher/logging"
+	"launcher/manager"
+	"launcher/manager/comp"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInstallQuilt(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	logging.Logger = logger.NewDefaultLogger()
+
+	services := newFakeServices(t)
+	services.serveVanilla("1.19", nil)
+	// quilt lists no stability, the newest build without a pre-release suffix is stable
+	services.serve("/v3/versions/loader/1.19", []byte(`[
+		{"loader": {"version": "0.17.2-beta.1"}},
+		{"loader": {"version": "0.17.1"}}
+	]`))
+	services.serveArtifact("/maven/org/quiltmc/quilt-loader/0.17.1/quilt-loader-0.17.1.jar", []byte("loader"))
+	services.serve("/v3/versions/loader/1.19/0.17.1/profile/json", []byte(fmt.Sprintf(`{
+		"id": "quilt-loader-0.17.1-1.19",
+		"inheritsFrom": "1.19",
+		"mainClass": "org.quiltmc.loader.impl.launch.knot.KnotClient",
+		"libraries": [{"name": "org.quiltmc:quilt-loader:0.17.1", "url": "%s/maven/"}]
+	}`, services.URL)))
+
+	if err := manager.InstallProfile(context.Background(), "1.19", manager.LoaderQuilt); err != nil {
+		t.Fatal(err)
+	}
+	installed, err := manager.ReadInstallJournal()
+	if err != nil || installed.Profile != "quilt-loader-0.17.1-1.19" {
+		t.Error("stable loader not installed", installed, err)
+	}
+	path := filepath.Join(comp.GetLibraryPath(), "org", "quiltmc", "quilt-loader", "0.17.1", "quilt-loader-0.17.1.jar")
+	if b, err := os.ReadFile(path); err != nil || string(b) != "loader" {
+		t.Error("loader library not installed", err)
+	}
+}
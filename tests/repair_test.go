@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/wailsapp/wails/v2/pkg/logger"
+	"launcher/logging"
+	"launcher/manager"
+	"os"
+	"testing"
+)
+
+func TestRepair(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	logging.Logger = logger.NewDefaultLogger()
+
+	icon, sound := []byte("icon"), []byte("sound")
+	index := []byte(fmt.Sprintf(`{"objects": {
+		"icons/icon.png": {"hash": "%s", "size": 4},
+		"sounds/sound.ogg": {"hash": "%s", "size": 5}
+	}}`, sha1Hex(icon), sha1Hex(sound)))
+	client, logConfig, library := []byte("client"), []byte("<Configuration/>"), []byte("library")
+	services := newFakeServices(t)
+	services.serve("/mc/game/version_manifest_v2.json", []byte(`{"latest": {}, "versions": []}`))
+	for _, object := range [][]byte{icon, sound} {
+		services.serve("/"+sha1Hex(object)[0:2]+"/"+sha1Hex(object), object)
+	}
+
+	writeVersion(t, "1.19", fmt.Sprintf(`{
+		"id": "1.19",
+		"mainClass": "net.minecraft.client.main.Main",
+		"assetIndex": {"id": "1.19", "url": "%s", "sha1": "%s"},
+		"downloads": {"client": {"url": "%s", "sha1": "%s", "size": 6}},
+		"logging": {"client": {"file": {"id": "client.xml", "url": "%s", "sha1": "%s", "size": 16}}},
+		"libraries": [{"name": "com.example:lib:1.0", "downloads": {"artifact": {
+			"path": "com/example/lib/1.0/lib-1.0.jar", "url": "%s", "sha1": "%s", "size": 7}}}]
+	}`, services.serve("/index.json", index), sha1Hex(index), services.serve("/client.jar", client), sha1Hex(client),
+		services.serve("/client.xml", logConfig), sha1Hex(logConfig), services.serve("/lib.jar", library), sha1Hex(library)))
+
+	games := manager.Explore()
+	if len(games) != 1 {
+		t.Fatal("profile not found")
+	}
+	game := games[0]
+	report, err := game.Repair(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Client || !report.AssetIndex || !report.LogConfig || len(report.Assets) != 2 || len(report.Libraries) != 1 {
+		t.Error("missing files not reported", report)
+	}
+	if report := game.Verify(); !report.IsValid() {
+		t.Fatal("profile still broken after repair", report)
+	}
+
+	// corrupt a single asset, only its object is downloaded again
+	corrupt := manager.Asset{Hash: sha1Hex(sound)}
+	if err := os.WriteFile(corrupt.GetObjectPath(), []byte("bad"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	services.takeRequests()
+	report, err = game.Repair(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Assets) != 1 || report.Assets[0] != "sounds/sound.ogg" || report.Client || len(report.Libraries) != 0 {
+		t.Error("unexpected report", report)
+	}
+	if requested := services.takeRequests(); len(requested) != 1 || requested[0] != "/"+sha1Hex(sound)[0:2]+"/"+sha1Hex(sound) {
+		t.Error("unexpected downloads", requested)
+	}
+}
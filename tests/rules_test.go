@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"encoding/json"
+	"launcher/manager"
+	"strings"
+	"testing"
+)
+
+func parseRules(t *testing.T, data string) []manager.Rule {
+	var rules []manager.Rule
+	if err := json.Unmarshal([]byte(data), &rules); err != nil {
+		t.Fatal(err)
+	}
+	return rules
+}
+
+func TestRules(t *testing.T) {
+	linux := manager.Environment{OS: "linux", Arch: "x86_64", OSVersion: "5.15.0"}
+	osx := manager.Environment{OS: "osx", Arch: "arm64", OSVersion: "12.4"}
+	windows := manager.Environment{OS: "windows", Arch: "x86", OSVersion: "10.0"}
+
+	allowExceptOSX := parseRules(t, `[{"action": "allow"}, {"action": "disallow", "os": {"name": "osx"}}]`)
+	if !manager.EvaluateRules(allowExceptOSX, linux) || manager.EvaluateRules(allowExceptOSX, osx) {
+		t.Error("disallow rule not honored")
+	}
+
+	onlyWindows := parseRules(t, `[{"action": "allow", "os": {"name": "windows"}}]`)
+	if manager.EvaluateRules(onlyWindows, linux) || !manager.EvaluateRules(onlyWindows, windows) {
+		t.Error("os rule not honored")
+	}
+
+	onlyX86 := parseRules(t, `[{"action": "allow", "os": {"arch": "x86"}}]`)
+	if manager.EvaluateRules(onlyX86, linux) || !manager.EvaluateRules(onlyX86, windows) {
+		t.Error("arch rule not honored")
+	}
+
+	windows10 := parseRules(t, `[{"action": "allow", "os": {"name": "windows", "version": "^10\\."}}]`)
+	if !manager.EvaluateRules(windows10, windows) {
+		t.Error("os version rule not honored")
+	}
+	if manager.EvaluateRules(windows10, manager.Environment{OS: "windows", OSVersion: "6.1"}) {
+		t.Error("os version rule matched an older version")
+	}
+
+	resolution := parseRules(t, `[{"action": "allow", "features": {"has_custom_resolution": true}}]`)
+	if manager.EvaluateRules(resolution, linux) {
+		t.Error("feature rule matched without the feature")
+	}
+	if !manager.EvaluateRules(resolution, linux.WithFeature(manager.FeatureCustomResolution, true)) {
+		t.Error("feature rule not honored")
+	}
+
+	if !manager.EvaluateRules(nil, osx) {
+		t.Error("no rules should allow everything")
+	}
+}
+
+func TestCommandLineForTarget(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var ver manager.Version
+	err := json.Unmarshal([]byte(`{
+		"id": "1.19",
+		"arguments": {
+			"game": ["--username", "${auth_player_name}",
+				{"rules": [{"action": "allow", "features": {"is_demo_user": true}}], "value": "--demo"},
+				{"rules": [{"action": "allow", "features": {"has_custom_resolution": true}}], "value": ["--width", "${resolution_width}", "--height", "${resolution_height}"]}],
+			"jvm": [{"rules": [{"action": "allow", "os": {"name": "osx"}}], "value": ["-XstartOnFirstThread"]},
+				{"rules": [{"action": "allow", "os": {"name": "windows", "arch": "x86"}}], "value": "-Xss1M"},
+				"-cp", "${classpath}"]
+		},
+		"libraries": [
+			{"name": "a:linux-only:1", "rules": [{"action": "allow", "os": {"name": "linux"}}]},
+			{"name": "a:everywhere:1"}
+		]
+	}`), &ver)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := manager.LaunchOptions{Width: 800, Height: 600, Env: manager.Environment{OS: "osx", Arch: "arm64"}}
+	jvm, game := ver.CreateCommandLine("client.jar", manager.LaunchPlaceholders{Username: "steve"}, opts, nil, nil)
+
+	if jvm[0] != "-XstartOnFirstThread" || len(jvm) != 3 {
+		t.Error("unexpected jvm arguments", jvm)
+	}
+	if strings.Contains(jvm[2], "linux-only") || !strings.Contains(jvm[2], "everywhere") {
+		t.Error("unexpected classpath", jvm[2])
+	}
+	if strings.Join(game, " ") != "--username steve --width 800 --height 600" {
+		t.Error("unexpected game arguments", game)
+	}
+}
+
+func TestLegacyCommandLine(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var ver manager.Version
+	err := json.Unmarshal([]byte(`{
+		"id": "1.8.9",
+		"minecraftArguments": "--username ${auth_player_name} --session ${auth_session} --userProperties ${user_properties}",
+		"libraries": [{"name": "a:everywhere:1"}]
+	}`), &ver)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := manager.LaunchOptions{Width: 854, Height: 480, Env: manager.Environment{OS: "windows", Arch: "x86", OSVersion: "10.0"}}
+	jvm, game := ver.CreateCommandLine("client.jar", manager.LaunchPlaceholders{
+		Username:         "steve",
+		NativesDirectory: "natives",
+		AuthSession:      "token:abc:123",
+		UserProperties:   "{}",
+	}, opts, nil, nil)
+
+	expected := []string{"-XX:HeapDumpPath=MojangTricksIntelDriversForPerformance_javaw.exe_minecraft.exe.heapdump", "-Dos.name=Windows 10", "-Dos.version=10.0", "-Xss1M", "-Djava.library.path=natives"}
+	for i, arg := range expected {
+		if jvm[i] != arg {
+			t.Errorf("unexpected jvm argument %d: %s", i, jvm[i])
+		}
+	}
+	if jvm[len(jvm)-2] != "-cp" {
+		t.Error("classpath not passed", jvm)
+	}
+	if strings.Join(game, " ") != "--username steve --session token:abc:123 --userProperties {} --width 854 --height 480" {
+		t.Error("unexpected game arguments", game)
+	}
+}
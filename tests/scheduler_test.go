@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/wailsapp/wails/v2/pkg/logger"
+	"launcher/logging"
+	"launcher/manager"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// concurrencyRecorder tracks the requests in flight on each host and overall
+type concurrencyRecorder struct {
+	lock     sync.Mutex
+	inFlight map[string]int
+	maxHost  map[string]int
+	total    int
+	maxTotal int
+}
+
+func (c *concurrencyRecorder) enter(host string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.inFlight[host]++
+	c.total++
+	if c.inFlight[host] > c.maxHost[host] {
+		c.maxHost[host] = c.inFlight[host]
+	}
+	if c.total > c.maxTotal {
+		c.maxTotal = c.total
+	}
+}
+
+func (c *concurrencyRecorder) leave(host string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.inFlight[host]--
+	c.total--
+}
+
+// serveLibraries publishes count libraries on handler and returns their entries in a version JSON
+func serveLibraries(t *testing.T, name string, count int, handler func(w http.ResponseWriter, r *http.Request, data []byte)) []string {
+	files := make(map[string][]byte)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r, files[r.URL.Path])
+	}))
+	t.Cleanup(server.Close)
+
+	var libraries []string
+	for i := 0; i < count; i++ {
+		data := []byte(fmt.Sprintf("%s %d", name, i))
+		files[fmt.Sprintf("/%s-%d.jar", name, i)] = data
+		libraries = append(libraries, fmt.Sprintf(`{"name": "com.example:%[1]s:%[2]d", "downloads": {"artifact": {
+			"path": "com/example/%[1]s/%[2]d/%[1]s-%[2]d.jar", "url": "%[3]s/%[1]s-%[2]d.jar", "sha1": "%[4]s", "size": %[5]d}}}`,
+			name, i, server.URL, sha1Hex(data), len(data)))
+	}
+	return libraries
+}
+
+func TestDownloadConcurrencyPerHost(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	logging.Logger = logger.NewDefaultLogger()
+
+	recorder := &concurrencyRecorder{inFlight: make(map[string]int), maxHost: make(map[string]int)}
+	slow := func(w http.ResponseWriter, r *http.Request, data []byte) {
+		recorder.enter(r.Host)
+		defer recorder.leave(r.Host)
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write(data)
+	}
+	libraries := append(serveLibraries(t, "first", 20, slow), serveLibraries(t, "second", 20, slow)...)
+	services := newFakeServices(t)
+	services.serveVanilla("1.19", nil, `"libraries": [`+strings.Join(libraries, ", ")+`]`)
+	services.serve("/v2/versions/loader/1.19", []byte(`[{"loader": {"version": "0.14.8", "stable": true}}]`))
+	services.serveFabricLoader("1.19", "0.14.8")
+
+	if err := manager.InstallProfile(context.Background(), "1.19", manager.LoaderFabric); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.maxHost) != 2 {
+		t.Fatal("libraries not requested from both hosts", recorder.maxHost)
+	}
+	for host, max := range recorder.maxHost {
+		if max > 8 {
+			t.Error("too many concurrent requests to", host, max)
+		}
+		if max < 2 {
+			t.Error("libraries of", host, "not downloaded concurrently")
+		}
+	}
+	if recorder.maxTotal > 16 {
+		t.Error("too many concurrent requests", recorder.maxTotal)
+	}
+}
+
+func TestCancelDownloads(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	logging.Logger = logger.NewDefaultLogger()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var lock sync.Mutex
+	requests := 0
+	libraries := serveLibraries(t, "library", 40, func(w http.ResponseWriter, r *http.Request, data []byte) {
+		lock.Lock()
+		requests++
+		lock.Unlock()
+		// the install is cancelled while the first downloads run
+		cancel()
+		<-r.Context().Done()
+	})
+	services := newFakeServices(t)
+	services.serveVanilla("1.19", nil, `"libraries": [`+strings.Join(libraries, ", ")+`]`)
+	services.serve("/v2/versions/loader/1.19", []byte(`[{"loader": {"version": "0.14.8", "stable": true}}]`))
+	services.serveFabricLoader("1.19", "0.14.8")
+
+	// the jobs not started are dropped, the error of ctx is returned instead of the failed downloads
+	err := manager.InstallProfile(ctx, "1.19", manager.LoaderFabric)
+	if !errors.Is(err, context.Canceled) {
+		t.Error("cancelled install did not return the error of its context", err)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if requests >= 40 {
+		t.Error("every library requested after the install was cancelled", requests)
+	}
+}
@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"launcher/manager"
+	"launcher/network"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeServices serves the files of every service the launcher talks to from memory.
+// It is the endpoint of all of them, so that installs run every step without the network.
+type fakeServices struct {
+	URL       string
+	lock      sync.Mutex
+	files     map[string][]byte
+	versions  []string // entries of the version manifest
+	requested []string
+}
+
+func newFakeServices(t *testing.T) *fakeServices {
+	s := &fakeServices{files: make(map[string][]byte)}
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	s.URL = server.URL
+	err := manager.Configure(network.Config{Endpoints: network.Endpoints{
+		Meta:      s.URL,
+		Resources: s.URL,
+		Libraries: s.URL,
+		Fabric:    s.URL,
+		Quilt:     s.URL,
+		Forge:     s.URL,
+		NeoForge:  s.URL,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = manager.Configure(network.Config{}) })
+	return s
+}
+
+func (s *fakeServices) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	b, ok := s.files[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	s.requested = append(s.requested, r.URL.Path)
+	_, _ = w.Write(b)
+}
+
+// serve publishes data at path and returns its url
+func (s *fakeServices) serve(path string, data []byte) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.files[path] = data
+	return s.URL + path
+}
+
+// serveArtifact publishes a maven artifact along with its checksum file
+func (s *fakeServices) serveArtifact(path string, data []byte) {
+	s.serve(path, data)
+	s.serve(path+".sha1", []byte(sha1Hex(data)))
+}
+
+// takeRequests returns the paths served since the last call
+func (s *fakeServices) takeRequests() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	requested := s.requested
+	s.requested = nil
+	return requested
+}
+
+// serveVanilla publishes the version in the version manifest with its client, asset index and java runtime.
+// The members are added to the version JSON, like its libraries.
+func (s *fakeServices) serveVanilla(id string, index []byte, members ...string) {
+	if index == nil {
+		index = []byte(`{"objects": {}}`)
+	}
+	client := []byte("client " + id)
+	version := []byte(fmt.Sprintf(`{
+		"id": "%[1]s",
+		"type": "release",
+		"mainClass": "net.minecraft.client.main.Main",
+		"javaVersion": {"component": "java-runtime-gamma", "majorVersion": 17},
+		"assetIndex": {"id": "%[1]s", "url": "%[2]s", "sha1": "%[3]s"},
+		"downloads": {"client": {"url": "%[4]s", "sha1": "%[5]s", "size": %[6]d}}%[7]s
+	}`, id, s.serve("/indexes/"+id+".json", index), sha1Hex(index),
+		s.serve("/clients/"+id+".jar", client), sha1Hex(client), len(client), joinMembers(members)))
+
+	s.lock.Lock()
+	s.versions = append(s.versions, fmt.Sprintf(`{"id": "%s", "type": "release", "url": "%s/versions/%s.json", "sha1": "%s"}`,
+		id, s.URL, id, sha1Hex(version)))
+	manifest := fmt.Sprintf(`{"latest": {"release": "%s"}, "versions": [%s]}`, id, strings.Join(s.versions, ", "))
+	s.lock.Unlock()
+	s.serve("/versions/"+id+".json", version)
+	s.serve("/mc/game/version_manifest_v2.json", []byte(manifest))
+	s.serveJavaRuntime("java-runtime-gamma", []byte(`{"files": {"bin": {"type": "directory"}}}`))
+}
+
+// serveJavaRuntime publishes the java runtime component with the files of manifest on every platform
+func (s *fakeServices) serveJavaRuntime(component string, manifest []byte) {
+	address := s.serve("/runtimes/"+component+".json", manifest)
+	var platforms []string
+	for _, p := range []string{"linux", "linux-i386", "mac-os", "mac-os-arm64", "windows-x64", "windows-x86", "windows-arm64"} {
+		platforms = append(platforms, fmt.Sprintf(`"%s": {"%s": [{"manifest": {"url": "%s", "sha1": "%s"}}]}`,
+			p, component, address, sha1Hex(manifest)))
+	}
+	s.serve("/v1/products/java-runtime/2ec0cc96c44e5a76b9c8b7c39df7210883d12871/all.json", []byte("{"+strings.Join(platforms, ", ")+"}"))
+}
+
+// serveFabricLoader publishes the fabric loader build for the minecraft version, its profile and its library
+func (s *fakeServices) serveFabricLoader(version string, build string) {
+	s.serveArtifact(fmt.Sprintf("/maven/net/fabricmc/fabric-loader/%[1]s/fabric-loader-%[1]s.jar", build), []byte("loader "+build))
+	s.serve("/v2/versions/loader/"+version+"/"+build+"/profile/json", []byte(fmt.Sprintf(`{
+		"id": "fabric-loader-%[1]s-%[2]s",
+		"inheritsFrom": "%[2]s",
+		"mainClass": "net.fabricmc.loader.impl.launch.knot.KnotClient",
+		"libraries": [{"name": "net.fabricmc:fabric-loader:%[1]s", "url": "%[3]s/maven/"}]
+	}`, build, version, s.URL)))
+}
+
+func joinMembers(members []string) string {
+	if len(members) == 0 {
+		return ""
+	}
+	return ",\n" + strings.Join(members, ",\n")
+}
+
+func sha1Hex(b []byte) string {
+	return fmt.Sprintf("%x", sha1.Sum(b))
+}
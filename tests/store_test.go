@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"launcher/manager"
+	"launcher/manager/comp"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCollectGarbage(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := comp.GetLauncherRoot()
+	write := func(rel string, data string) {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeVersion(t, "1.19", `{
+		"id": "1.19",
+		"assetIndex": {"id": "1.19"},
+		"logging": {"client": {"file": {"id": "client-1.12.xml"}}},
+		"libraries": [{"name": "com.example:used:1.0"}]
+	}`)
+	write("assets/indexes/1.19.json", `{"objects": {"icons/icon.png": {"hash": "aabbcc", "size": 4}}}`)
+	write("assets/objects/aa/aabbcc", "icon")
+	write("assets/log_cfgs/client-1.12.xml", "<Configuration/>")
+	write("libraries/com/example/used/1.0/used-1.0.jar", "used")
+
+	orphans := []string{
+		"assets/indexes/1.18.json",
+		"assets/log_cfgs/client-1.7.xml",
+		"assets/objects/dd/ddeeff",
+		"libraries/com/example/unused/1.0/unused-1.0.jar",
+	}
+	for _, orphan := range orphans {
+		write(orphan, "orphan")
+	}
+	write("libraries/com/example/partial/1.0/partial-1.0.jar.part", "resumable")
+
+	report, err := manager.CollectGarbage(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Files, orphans) || report.Bytes != int64(6*len(orphans)) {
+		t.Fatal("unexpected dry run report", report)
+	}
+	for _, orphan := range orphans {
+		if _, err := os.Stat(filepath.Join(root, orphan)); err != nil {
+			t.Error("dry run removed", orphan)
+		}
+	}
+
+	index, err := manager.ReadStoreIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	used := filepath.Join(root, "libraries", "com", "example", "used", "1.0", "used-1.0.jar")
+	if !reflect.DeepEqual(index.References(used), []string{"1.19"}) {
+		t.Error("library references not recorded", index.References(used))
+	}
+
+	report, err = manager.CollectGarbage(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Files) != len(orphans) {
+		t.Error("unexpected report", report)
+	}
+	for _, orphan := range orphans {
+		if _, err := os.Stat(filepath.Join(root, orphan)); !os.IsNotExist(err) {
+			t.Error("orphan kept", orphan)
+		}
+	}
+	for _, kept := range []string{"assets/indexes/1.19.json", "assets/objects/aa/aabbcc", "assets/log_cfgs/client-1.12.xml",
+		"libraries/com/example/used/1.0/used-1.0.jar", "libraries/com/example/partial/1.0/partial-1.0.jar.part"} {
+		if _, err := os.Stat(filepath.Join(root, kept)); err != nil {
+			t.Error("referenced file removed", kept)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "libraries", "com", "example", "unused")); !os.IsNotExist(err) {
+		t.Error("empty directory kept")
+	}
+}
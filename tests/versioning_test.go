@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"launcher/manager"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeVersion(t *testing.T, id string, data string) {
+	path := manager.GetVersionFilePath(id)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVersionInheritance(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	writeVersion(t, "1.19", `{
+		"id": "1.19",
+		"type": "release",
+		"mainClass": "net.minecraft.client.main.Main",
+		"assetIndex": {"id": "1.19"},
+		"arguments": {"game": ["--username", "${auth_player_name}"], "jvm": ["-cp", "${classpath}"]},
+		"libraries": [
+			{"name": "org.ow2.asm:asm:9.1", "downloads": {"artifact": {"path": "org/ow2/asm/asm/9.1/asm-9.1.jar"}}},
+			{"name": "com.mojang:brigadier:1.0.18", "downloads": {"artifact": {"path": "com/mojang/brigadier/1.0.18/brigadier-1.0.18.jar"}}}
+		]
+	}`)
+	writeVersion(t, "fabric-loader-0.14.8-1.19", `{
+		"id": "fabric-loader-0.14.8-1.19",
+		"inheritsFrom": "1.19",
+		"mainClass": "net.fabricmc.loader.impl.launch.knot.KnotClient",
+		"arguments": {"game": [], "jvm": ["-DFabricMcEmu= net.minecraft.client.main.Main "]},
+		"libraries": [
+			{"name": "org.ow2.asm:asm:9.3", "url": "https://maven.fabricmc.net/"}
+		]
+	}`)
+
+	ver, err := manager.LoadVersion("fabric-loader-0.14.8-1.19")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ver.MainClass != "net.fabricmc.loader.impl.launch.knot.KnotClient" {
+		t.Error("child main class not applied, got", ver.MainClass)
+	}
+	if ver.AssetIndex.ID != "1.19" || ver.Type != "release" {
+		t.Error("parent fields not inherited")
+	}
+	if ver.Jar != "1.19" || ver.GetMinecraftVersion() != "1.19" {
+		t.Error("client jar should come from the vanilla version, got", ver.Jar)
+	}
+	if len(ver.Arguments.JVM) != 3 || len(ver.Arguments.Game) != 2 {
+		t.Error("arguments not merged", ver.Arguments)
+	}
+	if len(ver.Libraries) != 2 {
+		t.Fatal("expected the child asm to replace the parent one, got", len(ver.Libraries))
+	}
+	artifact := ver.Libraries[0].GetArtifact()
+	if artifact.Path != filepath.Join("org", "ow2", "asm", "asm", "9.3", "asm-9.3.jar") {
+		t.Error("unexpected library path", artifact.Path)
+	}
+	if artifact.Url != "https://maven.fabricmc.net/org/ow2/asm/asm/9.3/asm-9.3.jar" {
+		t.Error("unexpected library url", artifact.Url)
+	}
+}